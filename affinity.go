@@ -0,0 +1,152 @@
+package gows
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAffinityTTL 默认亲和令牌有效期
+	DefaultAffinityTTL = 24 * time.Hour
+
+	// DefaultAffinityHeader 默认携带亲和令牌的请求头
+	DefaultAffinityHeader = "X-Gows-Affinity"
+
+	// DefaultAffinityQuery 默认携带亲和令牌的查询参数
+	DefaultAffinityQuery = "affinity"
+
+	// AffinityNodeHeader 节点不匹配时回复的目标节点响应头, 前端代理可据此重新路由
+	AffinityNodeHeader = "X-Gows-Node"
+)
+
+// AffinityToken 会话亲和令牌的内容
+type AffinityToken struct {
+	// Node 签发令牌的节点ID
+	Node string
+	// Session 会话ID
+	Session string
+	// Expires 过期时间
+	Expires time.Time
+}
+
+// AffinityOptions 会话亲和可选参数
+type AffinityOptions struct {
+	// Node 当前节点ID
+	Node string
+	// Key 签名密钥, 所有节点需使用相同的密钥
+	Key []byte
+	// TTL 令牌有效期, 默认24h
+	TTL time.Duration
+	// Header 携带令牌的请求头, 默认 X-Gows-Affinity
+	Header string
+	// Query 携带令牌的查询参数, 默认 affinity
+	Query string
+	// NodeURL 返回节点的直连地址, 非 nil 时节点不匹配的请求将被 307 重定向到该地址, 否则回复 421
+	NodeURL func(node string) string
+}
+
+// affinityKey AffinityToken 在请求上下文中的键
+type affinityKey struct{}
+
+// Affinity 会话亲和令牌, 令牌中嵌入签发节点ID并以 HMAC-SHA256 签名, 前端代理可按节点ID一致路由,
+// 恢复会话的连接落到其他节点时由 Middleware 给出重定向提示.
+// 令牌格式为 "base64url(节点ID\x00会话ID\x00过期时间).base64url(签名)".
+type Affinity struct {
+	// opts 配置
+	opts AffinityOptions
+}
+
+// NewAffinity 新建 Affinity实例.
+func NewAffinity(opts *AffinityOptions) *Affinity {
+	o := *opts
+	if o.TTL <= 0 {
+		o.TTL = DefaultAffinityTTL
+	}
+	if o.Header == "" {
+		o.Header = DefaultAffinityHeader
+	}
+	if o.Query == "" {
+		o.Query = DefaultAffinityQuery
+	}
+	return &Affinity{opts: o}
+}
+
+// Mint 为会话签发绑定当前节点的令牌
+func (a *Affinity) Mint(session string) string {
+	expires := time.Now().Add(a.opts.TTL).Unix()
+	payload := a.opts.Node + "\x00" + session + "\x00" + strconv.FormatInt(expires, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(a.mac([]byte(payload)))
+}
+
+// Verify 校验令牌, 签名错误、格式错误或已过期时返回 ErrInvalidSignature
+func (a *Affinity) Verify(token string) (*AffinityToken, error) {
+	i := strings.LastIndexByte(token, signatureSeparator)
+	if i < 0 {
+		return nil, ErrInvalidSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(sig, a.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+	parts := bytes.Split(payload, []byte{0})
+	if len(parts) != 3 {
+		return nil, ErrInvalidSignature
+	}
+	expires, err := strconv.ParseInt(string(parts[2]), 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return nil, ErrInvalidSignature
+	}
+	return &AffinityToken{Node: string(parts[0]), Session: string(parts[1]), Expires: time.Unix(expires, 0)}, nil
+}
+
+// Middleware 在升级前校验请求携带的令牌. 令牌属于其他节点时, 配置了 NodeURL 则 307 重定向到目标节点,
+// 否则回复 421, 两者均通过 X-Gows-Node 响应头给出目标节点. 未携带令牌或令牌无效时按新会话处理,
+// 有效的令牌可通过 AffinityFromContext 获取
+func (a *Affinity) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(a.opts.Header)
+		if token == "" {
+			token = r.URL.Query().Get(a.opts.Query)
+		}
+		if token != "" {
+			if t, err := a.Verify(token); err == nil {
+				if t.Node != a.opts.Node {
+					w.Header().Set(AffinityNodeHeader, t.Node)
+					if a.opts.NodeURL != nil {
+						http.Redirect(w, r, a.opts.NodeURL(t.Node), http.StatusTemporaryRedirect)
+					} else {
+						http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+					}
+					return
+				}
+				r = r.WithContext(context.WithValue(r.Context(), affinityKey{}, t))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AffinityFromContext 获取 Middleware 校验通过的令牌, 不存在时返回 nil
+func AffinityFromContext(ctx context.Context) *AffinityToken {
+	t, _ := ctx.Value(affinityKey{}).(*AffinityToken)
+	return t
+}
+
+// mac 计算签名
+func (a *Affinity) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, a.opts.Key)
+	_, _ = h.Write(payload)
+	return h.Sum(nil)
+}
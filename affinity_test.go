@@ -0,0 +1,49 @@
+package gows
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAffinity(t *testing.T) {
+	key := []byte("secret")
+	a := NewAffinity(&AffinityOptions{Node: "node-a", Key: key})
+	b := NewAffinity(&AffinityOptions{Node: "node-b", Key: key, NodeURL: func(node string) string {
+		return "wss://" + node + ".example.com/ws"
+	}})
+	token := a.Mint("s1")
+	got, err := b.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Node != "node-a" || got.Session != "s1" {
+		t.Fatalf("got %+v", got)
+	}
+	if _, err = NewAffinity(&AffinityOptions{Node: "node-a", Key: []byte("other")}).Verify(token); err != ErrInvalidSignature {
+		t.Fatalf("got %v, want ErrInvalidSignature", err)
+	}
+
+	var session string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t := AffinityFromContext(r.Context()); t != nil {
+			session = t.Session
+		}
+	})
+	r := httptest.NewRequest(http.MethodGet, "/ws?affinity="+token, nil)
+	w := httptest.NewRecorder()
+	a.Middleware(next).ServeHTTP(w, r)
+	if w.Code != http.StatusOK || session != "s1" {
+		t.Fatalf("got %d, session %q on owning node", w.Code, session)
+	}
+	w = httptest.NewRecorder()
+	b.Middleware(next).ServeHTTP(w, r)
+	if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != "wss://node-a.example.com/ws" || w.Header().Get(AffinityNodeHeader) != "node-a" {
+		t.Fatalf("got %d %v on other node", w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	NewAffinity(&AffinityOptions{Node: "node-c", Key: key}).Middleware(next).ServeHTTP(w, r)
+	if w.Code != http.StatusMisdirectedRequest {
+		t.Fatalf("got %d, want 421", w.Code)
+	}
+}
@@ -0,0 +1,236 @@
+package gows
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAPIKeyHeader 默认携带 API key 的请求头
+	DefaultAPIKeyHeader = "X-API-Key"
+
+	// DefaultAPIKeyQuery 默认携带 API key 的查询参数
+	DefaultAPIKeyQuery = "api_key"
+)
+
+// APIKey API key 及其限制
+type APIKey struct {
+	// ID 标识, 用于统计连接数与速率, 不应为 key 本身
+	ID string
+	// Name 名称
+	Name string
+	// MaxConnections 同时在线的最大连接数, 0 表示不限制
+	MaxConnections int
+	// RateLimit 所有连接合计每秒允许的入站消息数, 0 表示不限制
+	RateLimit float64
+	// Burst 允许的突发消息数, 默认为 RateLimit 向上取整
+	Burst int
+}
+
+// APIKeyStore API key 存储
+type APIKeyStore interface {
+	// Lookup 查找 key, 不存在时返回 nil
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// StaticAPIKeys 基于内存映射的 APIKeyStore
+type StaticAPIKeys map[string]*APIKey
+
+// Lookup 查找 key
+func (s StaticAPIKeys) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	return s[key], nil
+}
+
+// APIKeyOptions API key 认证可选参数
+type APIKeyOptions struct {
+	// Header 携带 key 的请求头, 默认 X-API-Key
+	Header string
+	// Query 携带 key 的查询参数, 默认 api_key
+	Query string
+}
+
+// APIKeyAuth 基于 API key 的认证, 适合机器之间的 WebSocket 集成.
+// Middleware 在升级前校验 key 与连接数, Limit 返回按 key 限速的入站拦截器
+type APIKeyAuth struct {
+	// opts 配置
+	opts APIKeyOptions
+	// store key 存储
+	store APIKeyStore
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// conns 每个 key 当前的连接数
+	conns map[string]int
+	// limiters 每个 key 的令牌桶
+	limiters map[string]*tokenBucket
+}
+
+// NewAPIKeyAuth 新建 APIKeyAuth实例, opts 可为 nil
+func NewAPIKeyAuth(store APIKeyStore, opts *APIKeyOptions) *APIKeyAuth {
+	o := APIKeyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Header == "" {
+		o.Header = DefaultAPIKeyHeader
+	}
+	if o.Query == "" {
+		o.Query = DefaultAPIKeyQuery
+	}
+	return &APIKeyAuth{
+		opts:     o,
+		store:    store,
+		conns:    make(map[string]int),
+		limiters: make(map[string]*tokenBucket),
+	}
+}
+
+// Authenticate 从请求头或查询参数中取出 key 并查找, 无效时返回 ErrInvalidAPIKey
+func (a *APIKeyAuth) Authenticate(r *http.Request) (*APIKey, error) {
+	key := r.Header.Get(a.opts.Header)
+	if key == "" {
+		key = r.URL.Query().Get(a.opts.Query)
+	}
+	if key == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	k, err := a.store.Lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return k, nil
+}
+
+// Middleware 在升级前校验 key, 无效时回复 401, 连接数达到上限时回复 429.
+// 连接数在 next 返回后释放, 因此 next 应阻塞至连接结束, 参见 README 中的示例.
+// 成功时可通过 APIKeyFromContext 获取 key
+func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k, err := a.Authenticate(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if !a.acquire(k) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		defer a.release(k)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, k)))
+	})
+}
+
+// Connections 获取 key 当前的连接数
+func (a *APIKeyAuth) Connections(k *APIKey) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.conns[k.ID]
+}
+
+// Limit 返回按 key 限速的入站拦截器, 同一 key 的所有连接共享速率.
+// 超出速率的消息被丢弃, 对信封格式的消息向客户端回复错误信封
+func (a *APIKeyAuth) Limit(k *APIKey) Interceptor {
+	if k.RateLimit <= 0 {
+		return func(conn *Connection, msg *Message) (*Message, error) {
+			return msg, nil
+		}
+	}
+	a.mutex.Lock()
+	bucket, ok := a.limiters[k.ID]
+	if !ok {
+		bucket = newTokenBucket(k.RateLimit, k.Burst)
+		a.limiters[k.ID] = bucket
+	}
+	a.mutex.Unlock()
+	return func(conn *Connection, msg *Message) (*Message, error) {
+		if bucket.take(conn.clock.Now()) {
+			return msg, nil
+		}
+		err := fmt.Errorf("%w: api key %s", ErrRateLimited, k.ID)
+		if e, decodeErr := DecodeMessageEnvelope(msg); decodeErr == nil {
+			_ = conn.WriteError(e, ErrorCodeRateLimited, err.Error())
+		}
+		return nil, err
+	}
+}
+
+// acquire 占用一个连接数, 达到上限时返回 false
+func (a *APIKeyAuth) acquire(k *APIKey) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if k.MaxConnections > 0 && a.conns[k.ID] >= k.MaxConnections {
+		return false
+	}
+	a.conns[k.ID]++
+	return true
+}
+
+// release 释放一个连接数
+func (a *APIKeyAuth) release(k *APIKey) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.conns[k.ID]--; a.conns[k.ID] <= 0 {
+		delete(a.conns, k.ID)
+	}
+}
+
+// apiKeyKey API key 在 context 中的键
+type apiKeyKey struct{}
+
+// APIKeyFromContext 获取 Middleware 写入的 API key
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(*APIKey)
+	return k, ok
+}
+
+// tokenBucket 令牌桶限速器
+type tokenBucket struct {
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// rate 每秒产生的令牌数
+	rate float64
+	// burst 桶容量
+	burst float64
+	// tokens 当前令牌数
+	tokens float64
+	// last 上次更新时间
+	last time.Time
+}
+
+// newTokenBucket 新建令牌桶, 初始为满, burst 不大于 0 时为 rate 向上取整
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = float64(int(rate))
+		if b < rate {
+			b++
+		}
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b}
+}
+
+// take 取出一个令牌, 令牌不足时返回 false
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	// 共享同一个令牌桶的连接可能使用不同的时钟, 时间回退时不补充令牌
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
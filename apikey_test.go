@@ -0,0 +1,68 @@
+package gows
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	key := &APIKey{ID: "k1", MaxConnections: 1}
+	auth := NewAPIKeyAuth(StaticAPIKeys{"secret": key}, nil)
+	entered, done := make(chan struct{}), make(chan struct{})
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k, _ := APIKeyFromContext(r.Context()); k != key {
+			t.Errorf("got key %v from context", k)
+		}
+		entered <- struct{}{}
+		<-done
+	}))
+	serve := func(target, header string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(DefaultAPIKeyHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := serve("/", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("got status %d for wrong key, want 401", code)
+	}
+	first := make(chan int)
+	go func() {
+		first <- serve("/?api_key=secret", "")
+	}()
+	<-entered
+	if code := serve("/", "secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d over connection limit, want 429", code)
+	}
+	close(done)
+	<-first
+	if n := auth.Connections(key); n != 0 {
+		t.Fatalf("got %d connections after handler returned, want 0", n)
+	}
+}
+
+func TestAPIKeyLimit(t *testing.T) {
+	key := &APIKey{ID: "k1", RateLimit: 1, Burst: 2}
+	auth := NewAPIKeyAuth(StaticAPIKeys{}, nil)
+	limit := auth.Limit(key)
+	conn := NewConnection()
+	msg := &Message{MessageType: TextMessage, Data: []byte("hi")}
+	for i := 0; i < 2; i++ {
+		if _, err := limit(conn, msg); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	if _, err := auth.Limit(key)(conn, msg); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited shared across connections", err)
+	}
+	// 令牌按连接的时钟补充
+	later := NewConnection(WithClock(skewClock{skew: time.Minute}))
+	if _, err := limit(later, msg); err != nil {
+		t.Fatalf("got %v, want tokens refilled on the connection clock", err)
+	}
+}
@@ -0,0 +1,265 @@
+package gows
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// AuditEventConnect 连接建立
+	AuditEventConnect = "connect"
+
+	// AuditEventAuth 鉴权结果
+	AuditEventAuth = "auth"
+
+	// AuditEventDisconnect 连接断开
+	AuditEventDisconnect = "disconnect"
+
+	// DefaultAuditTimeout 默认 HTTP 审计请求超时时间
+	DefaultAuditTimeout = 5 * time.Second
+
+	// DefaultAuditQueueSize 默认 HTTP 审计事件待发送队列大小
+	DefaultAuditQueueSize = 1024
+)
+
+// AuditEvent 连接生命周期审计事件
+type AuditEvent struct {
+	// Type 事件类型
+	Type string `json:"type"`
+	// ConnID 连接ID
+	ConnID string `json:"conn_id"`
+	// UserID 用户ID
+	UserID string `json:"user_id,omitempty"`
+	// RemoteIP 远程IP
+	RemoteIP string `json:"remote_ip,omitempty"`
+	// Success 鉴权是否成功, 仅 auth 事件有效
+	Success bool `json:"success,omitempty"`
+	// Reason 断开原因或鉴权失败原因
+	Reason string `json:"reason,omitempty"`
+	// Time 事件时间
+	Time time.Time `json:"time"`
+}
+
+// AuditSink 审计事件输出
+type AuditSink interface {
+	Write(event *AuditEvent) error
+}
+
+// writerAuditSink 以 JSON Lines 格式写入 io.Writer 的审计输出
+type writerAuditSink struct {
+	// mutex 保证每行完整写入
+	mutex sync.Mutex
+	// w 输出目标
+	w io.Writer
+}
+
+// NewWriterAuditSink 以 JSON Lines 格式写入 w, 可配合 log/syslog 的 *syslog.Writer 输出到 syslog
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{w: w}
+}
+
+// NewFileAuditSink 以 JSON Lines 格式追加写入文件
+func NewFileAuditSink(path string) (AuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterAuditSink(f), nil
+}
+
+// Write 写入事件
+func (s *writerAuditSink) Write(event *AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// httpAuditSink 以 JSON POST 到指定地址的审计输出, 事件经有界队列由后台协程发送
+type httpAuditSink struct {
+	// url 接收地址
+	url string
+	// client http 客户端
+	client *http.Client
+	// queue 待发送队列
+	queue chan *AuditEvent
+	// closeChan 关闭通知
+	closeChan chan struct{}
+	// closeOnce 保护 closeChan 只被关闭一次
+	closeOnce sync.Once
+	// wg 等待发送协程退出
+	wg sync.WaitGroup
+	// mutex 保护 err
+	mutex sync.Mutex
+	// err 后台发送的最近一次错误, 在下一次 Write 时返回
+	err error
+}
+
+// NewHTTPAuditSink 将每个事件以 JSON POST 到 url, client 为 nil 时使用超时为 DefaultAuditTimeout 的客户端.
+// Write 只将事件放入有界队列, 不会阻塞连接的回调; 队列满时返回 ErrAuditQueueFull,
+// 后台发送失败的错误在下一次 Write 时返回. 返回值实现了 io.Closer, Close 等待队列中的事件发送完毕
+func NewHTTPAuditSink(url string, client *http.Client) AuditSink {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultAuditTimeout}
+	}
+	s := &httpAuditSink{
+		url:       url,
+		client:    client,
+		queue:     make(chan *AuditEvent, DefaultAuditQueueSize),
+		closeChan: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// Write 将事件放入待发送队列, 返回上一次后台发送的错误
+func (s *httpAuditSink) Write(event *AuditEvent) error {
+	select {
+	case <-s.closeChan:
+		return ErrAuditSinkClosed
+	default:
+	}
+	select {
+	case s.queue <- event:
+	default:
+		return ErrAuditQueueFull
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// Close 停止接收新事件, 等待队列中的事件发送完毕
+func (s *httpAuditSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// loop 发送协程
+func (s *httpAuditSink) loop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case event := <-s.queue:
+			s.send(event)
+		case <-s.closeChan:
+			for {
+				select {
+				case event := <-s.queue:
+					s.send(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 发送事件并记录错误
+func (s *httpAuditSink) send(event *AuditEvent) {
+	if err := s.post(event); err != nil {
+		s.mutex.Lock()
+		s.err = fmt.Errorf("previous %s event of connection %s: %w", event.Type, event.ConnID, err)
+		s.mutex.Unlock()
+	}
+}
+
+// post 发送一次请求, 非 2xx 响应视为失败
+func (s *httpAuditSink) post(event *AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Auditor 记录连接生命周期审计事件. OnOpen 与 OnClose 可直接作为连接的回调使用.
+type Auditor struct {
+	// sink 审计输出
+	sink AuditSink
+	// logger 日志, 记录写入失败
+	logger Logger
+}
+
+// NewAuditor 新建 Auditor实例, logger 为 nil 时不输出写入失败日志.
+func NewAuditor(sink AuditSink, logger Logger) *Auditor {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	return &Auditor{sink: sink, logger: logger}
+}
+
+// OnOpen 连接开启回调, 记录 connect 事件
+func (a *Auditor) OnOpen(conn *Connection) {
+	a.Record(a.event(AuditEventConnect, conn))
+}
+
+// OnClose 连接关闭回调, 记录 disconnect 事件
+func (a *Auditor) OnClose(conn *Connection, reason error) {
+	event := a.event(AuditEventDisconnect, conn)
+	if reason != nil {
+		event.Reason = reason.Error()
+	}
+	a.Record(event)
+}
+
+// Auth 记录鉴权结果, err 为 nil 表示鉴权成功
+func (a *Auditor) Auth(conn *Connection, err error) {
+	event := a.event(AuditEventAuth, conn)
+	event.Success = err == nil
+	if err != nil {
+		event.Reason = err.Error()
+	}
+	a.Record(event)
+}
+
+// Record 记录事件
+func (a *Auditor) Record(event *AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if err := a.sink.Write(event); err != nil {
+		a.logger.Printf("gows: audit %s event of connection %s failed: %v", event.Type, event.ConnID, err)
+	}
+}
+
+// event 根据连接构造事件
+func (a *Auditor) event(typ string, conn *Connection) *AuditEvent {
+	event := &AuditEvent{
+		Type:   typ,
+		ConnID: conn.GetConnID(),
+		UserID: conn.GetUserID(),
+	}
+	if conn.conn != nil {
+		event.RemoteIP = conn.GetRemoteAddr().String()
+		if host, _, err := net.SplitHostPort(event.RemoteIP); err == nil {
+			event.RemoteIP = host
+		}
+	}
+	return event
+}
@@ -0,0 +1,84 @@
+package gows
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditor(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAuditor(NewWriterAuditSink(&buf), nil)
+	conn := NewConnection()
+	conn.SetUserID("u1")
+	a.Auth(conn, errors.New("bad token"))
+	a.OnClose(conn, ErrHeartbeatTimeout)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d events, want 2", len(lines))
+	}
+	for i, want := range []AuditEvent{
+		{Type: AuditEventAuth, UserID: "u1", Reason: "bad token"},
+		{Type: AuditEventDisconnect, UserID: "u1", Reason: ErrHeartbeatTimeout.Error()},
+	} {
+		got := AuditEvent{}
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != want.Type || got.UserID != want.UserID || got.Reason != want.Reason || got.ConnID != conn.GetConnID() || got.Success {
+			t.Fatalf("event %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	release := make(chan struct{})
+	events := make(chan *AuditEvent, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		event := &AuditEvent{}
+		_ = json.NewDecoder(r.Body).Decode(event)
+		select {
+		case events <- event:
+		default:
+		}
+		if event.Type == AuditEventAuth {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	sink := NewHTTPAuditSink(srv.URL, nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.Write(&AuditEvent{Type: AuditEventAuth, ConnID: "1"})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a slow endpoint")
+	}
+	close(release)
+	<-events
+	for i := 0; i < 100; i++ {
+		if err := sink.Write(&AuditEvent{Type: AuditEventConnect, ConnID: "2"}); err != nil {
+			break
+		}
+		if i == 99 {
+			t.Fatal("failed delivery not reported on a later Write")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = sink.(io.Closer).Close()
+	if err := sink.Write(&AuditEvent{Type: AuditEventConnect}); !errors.Is(err, ErrAuditSinkClosed) {
+		t.Fatalf("got %v, want ErrAuditSinkClosed", err)
+	}
+}
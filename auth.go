@@ -0,0 +1,43 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// DefaultAuthTimeout 默认等待认证帧的超时时间
+const DefaultAuthTimeout = 10 * time.Second
+
+// Authenticator 校验认证帧, 通过时可调用 SetUserID 等绑定身份, 返回 error 时连接以 1008 (policy violation) 关闭
+type Authenticator func(conn *Connection, msg *Message) error
+
+// authenticate 等待并校验认证帧, 超时或校验失败时关闭连接
+func (c *Connection) authenticate() error {
+	timeout := c.authTimeout
+	if timeout <= 0 {
+		timeout = DefaultAuthTimeout
+	}
+	timer := c.clock.NewTimer(timeout)
+	defer timer.Stop()
+	msg, err := c.receive(timer.C())
+	switch err {
+	case nil:
+		// 认证函数 panic 时连接已由 protect 以 ErrPanic 关闭
+		panicked := true
+		c.protect(func() {
+			err = c.authenticator(c, msg)
+			panicked = false
+		})
+		if panicked {
+			return c.CloseReason()
+		}
+	case ErrReceiveTimeout:
+		err = ErrAuthTimeout
+	default:
+		return err
+	}
+	if err != nil {
+		_ = c.closeWithCode(websocket.ClosePolicyViolation, err)
+	}
+	return err
+}
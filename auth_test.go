@@ -0,0 +1,70 @@
+package gows
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthFrame(t *testing.T) {
+	errBadToken := errors.New("bad token")
+	results := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithAuthFrame(50*time.Millisecond, func(conn *Connection, msg *Message) error {
+			if string(msg.Data) == "panic" {
+				panic("boom")
+			}
+			if string(msg.Data) != "token" {
+				return errBadToken
+			}
+			conn.SetUserID("u1")
+			return nil
+		}))
+		err := conn.Open(w, r)
+		if err == nil && conn.GetUserID() != "u1" {
+			err = errors.New("user not bound")
+		}
+		results <- err
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	for _, c := range []struct {
+		frame string
+		want  error
+	}{
+		{"token", nil},
+		{"wrong", errBadToken},
+		{"panic", ErrPanic},
+		{"", ErrAuthTimeout},
+	} {
+		client, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.frame != "" {
+			_ = client.WriteMessage(websocket.TextMessage, []byte(c.frame))
+		}
+		if err = <-results; !errors.Is(err, c.want) {
+			t.Fatalf("%q: got %v, want %v", c.frame, err, c.want)
+		}
+		if c.want != nil {
+			_ = client.SetReadDeadline(time.Now().Add(time.Second))
+			_, _, err = client.ReadMessage()
+			if err == nil {
+				t.Fatalf("%q: connection not closed", c.frame)
+			}
+			// 认证函数 panic 时连接直接关闭, 不发送关闭帧
+			if c.want != ErrPanic && !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Fatalf("%q: got %v, want policy violation close", c.frame, err)
+			}
+		}
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,174 @@
+package gows
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// BatchEvent 批量帧的事件名, 消息体为信封数组
+	BatchEvent = "batch"
+
+	// DefaultBatchDelay 默认批量帧最长等待时间
+	DefaultBatchDelay = 10 * time.Millisecond
+
+	// DefaultBatchMessages 默认每个批量帧最多包含的信封数
+	DefaultBatchMessages = 64
+
+	// DefaultBatchBytes 默认每个批量帧最多包含的字节数
+	DefaultBatchBytes = 32 * 1024
+)
+
+// BatchOptions 批量帧可选参数. 开启后 WriteBatched 写入的信封将在短时间内合并为一个批量帧发送,
+// 收到的批量帧也会被拆分为单独的消息, 适合高频的小消息以减少帧开销.
+// 收到的批量帧在入站拦截器之前拆分, 每条消息分别经过入站拦截器, 因此不能与 Encryptor、Signer 等
+// 作用于整个帧的拦截器同时使用
+type BatchOptions struct {
+	// MaxDelay 第一条信封等待合并的最长时间, 默认10ms
+	MaxDelay time.Duration
+	// MaxMessages 每个批量帧最多包含的信封数, 达到后立即发送, 默认64
+	MaxMessages int
+	// MaxBytes 每个批量帧最多包含的字节数, 达到后立即发送, 默认32KB
+	MaxBytes int
+}
+
+// batcher 待合并的信封
+type batcher struct {
+	// opts 配置
+	opts BatchOptions
+	// envelopes 待发送的信封
+	envelopes []*Envelope
+	// encoded 已编码的信封, 与 envelopes 一一对应
+	encoded []json.RawMessage
+	// size 已编码信封的总字节数
+	size int
+	// timerDone 最长等待时间定时器的停止信号, 未启动时为 nil
+	timerDone chan struct{}
+}
+
+// newBatcher 新建 batcher, opts 为 nil 时不开启批量帧
+func newBatcher(opts *BatchOptions) *batcher {
+	if opts == nil {
+		return nil
+	}
+	o := *opts
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = DefaultBatchDelay
+	}
+	if o.MaxMessages <= 0 {
+		o.MaxMessages = DefaultBatchMessages
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultBatchBytes
+	}
+	return &batcher{opts: o}
+}
+
+// take 取出尚未发送的信封并停止定时器, 调用方需持有 batchMutex
+func (b *batcher) take() ([]*Envelope, []json.RawMessage) {
+	if b.timerDone != nil {
+		close(b.timerDone)
+		b.timerDone = nil
+	}
+	envelopes, encoded := b.envelopes, b.encoded
+	b.envelopes, b.encoded, b.size = nil, nil, 0
+	return envelopes, encoded
+}
+
+// WriteBatched 写入信封, 开启批量帧时将与其他信封合并后发送, 否则等同于 WriteEnvelope.
+// 连接关闭时尚未发送的信封将被丢弃, 需要时应先调用 FlushBatch
+func (c *Connection) WriteBatched(e *Envelope) error {
+	if c.batch == nil {
+		return c.WriteEnvelope(e)
+	}
+	data, err := e.Encode()
+	if err != nil {
+		return err
+	}
+	c.batchMutex.Lock()
+	b := c.batch
+	b.envelopes = append(b.envelopes, e)
+	b.encoded = append(b.encoded, data)
+	b.size += len(data)
+	if len(b.envelopes) >= b.opts.MaxMessages || b.size >= b.opts.MaxBytes {
+		envelopes, encoded := b.take()
+		c.batchMutex.Unlock()
+		return c.writeBatch(envelopes, encoded)
+	}
+	if b.timerDone == nil {
+		b.timerDone = make(chan struct{})
+		c.startBatchTimer(b.timerDone)
+	}
+	c.batchMutex.Unlock()
+	return nil
+}
+
+// startBatchTimer 启动最长等待时间定时器, 到期后发送尚未发送的信封
+func (c *Connection) startBatchTimer(done chan struct{}) {
+	timer := c.clock.NewTimer(c.batch.opts.MaxDelay)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			if err := c.FlushBatch(); err != nil {
+				c.logger.Printf("gows: connection %s flush batch error: %v", c.id, err)
+			}
+		case <-done:
+		case <-c.closeChan:
+		}
+	}()
+}
+
+// FlushBatch 立即发送尚未发送的信封
+func (c *Connection) FlushBatch() error {
+	if c.batch == nil {
+		return nil
+	}
+	c.batchMutex.Lock()
+	envelopes, encoded := c.batch.take()
+	c.batchMutex.Unlock()
+	return c.writeBatch(envelopes, encoded)
+}
+
+// writeBatch 发送取出的信封, 只有一条时不封装为批量帧
+func (c *Connection) writeBatch(envelopes []*Envelope, encoded []json.RawMessage) error {
+	switch len(envelopes) {
+	case 0:
+		return nil
+	case 1:
+		return c.WriteEnvelope(envelopes[0])
+	}
+	e, err := NewEnvelope(BatchEvent, encoded)
+	if err != nil {
+		return err
+	}
+	return c.WriteEnvelope(e)
+}
+
+// unbatch 将批量帧拆分为单独的消息, 不是批量帧时返回 false
+func (c *Connection) unbatch(msg *Message) ([]*Message, bool) {
+	e, err := DecodeMessageEnvelope(msg)
+	if err != nil || e.Event != BatchEvent {
+		return nil, false
+	}
+	envelopes, err := DecodeBatch(e)
+	if err != nil {
+		c.logger.Printf("gows: connection %s inbound batch dropped: %v", c.id, err)
+		c.drop(msg, false, err)
+		return nil, true
+	}
+	msgs := make([]*Message, len(envelopes))
+	for i, data := range envelopes {
+		msgs[i] = &Message{MessageType: msg.MessageType, Data: data}
+	}
+	return msgs, true
+}
+
+// DecodeBatch 解码批量帧的消息体, 返回其中已编码的信封
+func DecodeBatch(e *Envelope) ([]json.RawMessage, error) {
+	var envelopes []json.RawMessage
+	if err := e.Bind(&envelopes); err != nil {
+		return nil, err
+	}
+	return envelopes, nil
+}
@@ -0,0 +1,141 @@
+package gows
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatching(t *testing.T) {
+	received := make(chan string, 2)
+	client := newTestServer(t, WithBatching(&BatchOptions{MaxDelay: 20 * time.Millisecond}), func(conn *Connection) {
+		for _, event := range []string{"a", "b", "c"} {
+			e, _ := NewEnvelope(event, nil)
+			_ = conn.WriteBatched(e)
+		}
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			e, _ := DecodeEnvelope(msg.Data)
+			received <- e.Event
+		}
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := DecodeEnvelope(data)
+	if err != nil || e.Event != BatchEvent {
+		t.Fatalf("got %s, want a batch frame", data)
+	}
+	envelopes, err := DecodeBatch(e)
+	if err != nil || len(envelopes) != 3 {
+		t.Fatalf("got %d envelopes, want 3: %v", len(envelopes), err)
+	}
+
+	x, _ := NewEnvelope("x", nil)
+	y, _ := NewEnvelope("y", nil)
+	xData, _ := x.Encode()
+	yData, _ := y.Encode()
+	batch, _ := NewEnvelope(BatchEvent, []json.RawMessage{xData, yData})
+	msg, _ := batch.Message()
+	if err = client.WriteMessage(websocket.TextMessage, msg.Data); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"x", "y"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q not delivered", want)
+		}
+	}
+}
+
+func TestBatchingInterceptsInnerMessages(t *testing.T) {
+	rbac := NewRBAC(func(conn *Connection) []string { return []string{"user"} })
+	rbac.Allow("user", "chat")
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithBatching(nil))
+		conn.UseInbound(rbac.Intercept)
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			e, _ := DecodeEnvelope(msg.Data)
+			received <- e.Event
+		}
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	chat, _ := NewEnvelope("chat", nil)
+	del, _ := NewEnvelope("delete", nil)
+	chatData, _ := chat.Encode()
+	delData, _ := del.Encode()
+	batch, _ := NewEnvelope(BatchEvent, []json.RawMessage{delData, chatData})
+	data, _ := batch.Encode()
+	if err = client.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-received:
+		if event != "chat" {
+			t.Fatalf("got %q, want only chat delivered", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("got unexpected %q past RBAC", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBatchingSingleEnvelopeBinary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithSubprotocols(BinaryEnvelopeSubprotocol), WithBatching(&BatchOptions{MaxDelay: 10 * time.Millisecond}))
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		e, _ := NewEnvelope("hello", nil)
+		_ = conn.WriteBatched(e)
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	dialer := websocket.Dialer{Subprotocols: []string{BinaryEnvelopeSubprotocol}}
+	client, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	msgType, data, err := client.ReadMessage()
+	if err != nil || msgType != websocket.BinaryMessage {
+		t.Fatalf("got type %d, %v, want a binary envelope", msgType, err)
+	}
+	if e, err := DecodeBinaryEnvelope(data); err != nil || e.Event != "hello" {
+		t.Fatalf("got %+v, %v", e, err)
+	}
+}
@@ -0,0 +1,100 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"testing"
+)
+
+// drain 持续读取客户端消息直到连接关闭
+func drain(client *websocket.Conn) {
+	for {
+		if _, _, err := client.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// newBenchConn 建立一对连接, 返回服务端连接与客户端连接, 客户端丢弃收到的所有消息, 调用方需关闭客户端
+func newBenchConn(b *testing.B, opts ...Option) (*Connection, *testClient) {
+	conns := make(chan *Connection, 1)
+	client := newTestServer(b, buildOptions(opts), func(conn *Connection) {
+		conns <- conn
+		_, _ = conn.Receive()
+	})
+	go drain(client.Conn)
+	return <-conns, client
+}
+
+func BenchmarkEcho(b *testing.B) {
+	client := newTestServer(b, nil, func(conn *Connection) {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			_ = conn.Write(msg)
+		}
+	})
+	defer client.Close()
+	data := make([]byte, 128)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := client.ReadMessage(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	conns := make([]*Connection, 100)
+	for i := range conns {
+		var client *testClient
+		conns[i], client = newBenchConn(b)
+		defer client.Close()
+	}
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 128)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, conn := range conns {
+			if err := conn.Write(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteContention(b *testing.B) {
+	conn, client := newBenchConn(b)
+	defer client.Close()
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 128)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := conn.Write(msg); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkWriteInterceptors(b *testing.B) {
+	conn, client := newBenchConn(b, WithLatencyTracking())
+	defer client.Close()
+	conn.UseOutbound(NewSigner(StaticKey([]byte("secret"))).Sign)
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 128)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := conn.Write(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package gows
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerWindow 默认统计窗口
+	DefaultBreakerWindow = 10 * time.Second
+
+	// DefaultBreakerMinRequests 默认统计窗口内触发熔断所需的最少请求数
+	DefaultBreakerMinRequests = 20
+
+	// DefaultBreakerFailureRatio 默认触发熔断的失败比例
+	DefaultBreakerFailureRatio = 0.5
+
+	// DefaultBreakerOpenTimeout 默认熔断持续时间
+	DefaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	// BreakerClosed 正常放行
+	BreakerClosed BreakerState = iota
+	// BreakerOpen 熔断中, 直接拒绝
+	BreakerOpen
+	// BreakerHalfOpen 熔断超时后放行一个探测请求
+	BreakerHalfOpen
+)
+
+// BreakerOptions 熔断器可选参数
+type BreakerOptions struct {
+	// Window 统计窗口, 默认10s
+	Window time.Duration
+	// MinRequests 统计窗口内触发熔断所需的最少请求数, 默认20
+	MinRequests int
+	// FailureRatio 触发熔断的失败比例, 默认0.5
+	FailureRatio float64
+	// SlowThreshold 处理耗时超过该值视为失败, 为 0 时不统计耗时
+	SlowThreshold time.Duration
+	// OpenTimeout 熔断持续时间, 之后进入半开状态放行一个探测请求, 默认30s
+	OpenTimeout time.Duration
+}
+
+// CircuitBreaker 消息处理函数熔断器. 处理函数返回错误、panic 或超时的比例超过阈值时熔断,
+// 熔断期间直接向客户端回复结构化错误, 保护下游依赖.
+type CircuitBreaker struct {
+	// opts 配置
+	opts BreakerOptions
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// state 当前状态
+	state BreakerState
+	// windowStart 当前统计窗口开始时间
+	windowStart time.Time
+	// requests 当前窗口请求数
+	requests int
+	// failures 当前窗口失败数
+	failures int
+	// openedAt 熔断开始时间
+	openedAt time.Time
+	// probing 半开状态下是否已有探测请求
+	probing bool
+	// generation 状态代数, 每次状态切换时递增, 用于忽略切换前放行的请求的结果
+	generation uint64
+	// clock 时钟, 使用最近一次处理的连接的时钟
+	clock Clock
+}
+
+// NewCircuitBreaker 新建 CircuitBreaker实例.
+func NewCircuitBreaker(opts ...*BreakerOptions) *CircuitBreaker {
+	b := &CircuitBreaker{clock: realClock{}}
+	if len(opts) > 0 && opts[0] != nil {
+		b.opts = *opts[0]
+	}
+	if b.opts.Window <= 0 {
+		b.opts.Window = DefaultBreakerWindow
+	}
+	if b.opts.MinRequests <= 0 {
+		b.opts.MinRequests = DefaultBreakerMinRequests
+	}
+	if b.opts.FailureRatio <= 0 {
+		b.opts.FailureRatio = DefaultBreakerFailureRatio
+	}
+	if b.opts.OpenTimeout <= 0 {
+		b.opts.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	return b
+}
+
+// Wrap 使用熔断器包装处理函数
+func (b *CircuitBreaker) Wrap(fn func(conn *Connection, msg *Message) error) Handler {
+	return func(conn *Connection, msg *Message) {
+		generation, ok := b.allow(conn.clock)
+		if !ok {
+			ref, _ := DecodeMessageEnvelope(msg)
+			_ = conn.WriteError(ref, ErrorCodeUnavailable, ErrBreakerOpen.Error())
+			return
+		}
+		start := conn.clock.Now()
+		failed := true
+		defer func() {
+			now := conn.clock.Now()
+			if b.opts.SlowThreshold > 0 && now.Sub(start) > b.opts.SlowThreshold {
+				failed = true
+			}
+			b.record(generation, failed, now)
+		}()
+		failed = fn(conn, msg) != nil
+	}
+}
+
+// State 获取熔断器状态
+func (b *CircuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == BreakerOpen && b.clock.Now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow 按连接的时钟判断是否放行请求, 放行时返回当前的状态代数
+func (b *CircuitBreaker) allow(clock Clock) (uint64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.clock = clock
+	switch b.state {
+	case BreakerOpen:
+		if clock.Now().Sub(b.openedAt) < b.opts.OpenTimeout {
+			return 0, false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			return 0, false
+		}
+		b.probing = true
+	}
+	return b.generation, true
+}
+
+// setState 切换状态并递增状态代数, 调用方需持有 mutex
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	b.generation++
+}
+
+// record 记录 now 时的处理结果, generation 为放行时的状态代数. 状态已切换时忽略结果,
+// 避免关闭状态下放行、半开状态下才完成的请求被当作探测结果
+func (b *CircuitBreaker) record(generation uint64, failed bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if generation != b.generation {
+		return
+	}
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if failed {
+			b.setState(BreakerOpen)
+			b.openedAt = now
+		} else {
+			b.setState(BreakerClosed)
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		return
+	}
+	if now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.state == BreakerClosed && b.requests >= b.opts.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.opts.FailureRatio {
+		b.setState(BreakerOpen)
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,104 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(&BreakerOptions{MinRequests: 2, OpenTimeout: 50 * time.Millisecond})
+	fail := true
+	calls := 0
+	h := b.Wrap(func(conn *Connection, msg *Message) error {
+		calls++
+		if fail {
+			return errors.New("downstream error")
+		}
+		return nil
+	})
+	conn := NewConnection()
+	h(conn, &Message{})
+	h(conn, &Message{})
+	if b.State() != BreakerOpen {
+		t.Fatalf("got state %v, want open", b.State())
+	}
+	h(conn, &Message{})
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2 while open", calls)
+	}
+	if len(conn.outChan) != 1 {
+		t.Fatal("error reply not written while open")
+	}
+	time.Sleep(60 * time.Millisecond)
+	fail = false
+	h(conn, &Message{})
+	if calls != 3 || b.State() != BreakerClosed {
+		t.Fatalf("got %d calls and state %v, want probe to close breaker", calls, b.State())
+	}
+}
+
+func TestCircuitBreakerClock(t *testing.T) {
+	b := NewCircuitBreaker(&BreakerOptions{MinRequests: 1, OpenTimeout: time.Hour})
+	fail := true
+	h := b.Wrap(func(conn *Connection, msg *Message) error {
+		if fail {
+			return errors.New("downstream error")
+		}
+		return nil
+	})
+	h(NewConnection(), &Message{})
+	if b.State() != BreakerOpen {
+		t.Fatalf("got state %v, want open", b.State())
+	}
+	// 熔断时长按连接的时钟计算
+	later := NewConnection(WithClock(skewClock{skew: 2 * time.Hour}))
+	fail = false
+	h(later, &Message{})
+	if b.State() != BreakerClosed {
+		t.Fatalf("got state %v, want probe on the connection clock to close breaker", b.State())
+	}
+}
+
+func TestCircuitBreakerStaleResult(t *testing.T) {
+	b := NewCircuitBreaker(&BreakerOptions{MinRequests: 1, OpenTimeout: time.Hour})
+	started := make(chan struct{}, 2)
+	release := map[string]chan struct{}{"slow": make(chan struct{}), "probe": make(chan struct{})}
+	h := b.Wrap(func(conn *Connection, msg *Message) error {
+		switch name := string(msg.Data); name {
+		case "slow":
+			started <- struct{}{}
+			<-release[name]
+			return nil
+		case "probe":
+			started <- struct{}{}
+			<-release[name]
+		}
+		return errors.New("downstream error")
+	})
+	done := make(chan struct{}, 2)
+	run := func(conn *Connection, name string) {
+		h(conn, &Message{Data: []byte(name)})
+		done <- struct{}{}
+	}
+	// 关闭状态下放行的慢请求
+	go run(NewConnection(), "slow")
+	<-started
+	h(NewConnection(), &Message{Data: []byte("fail")})
+	if b.State() != BreakerOpen {
+		t.Fatalf("got state %v, want open", b.State())
+	}
+	go run(NewConnection(WithClock(skewClock{skew: 2 * time.Hour})), "probe")
+	<-started
+	// 慢请求在半开状态下成功完成, 不应被当作探测结果
+	close(release["slow"])
+	<-done
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("got state %v, want half-open until the probe finishes", b.State())
+	}
+	close(release["probe"])
+	<-done
+	if b.state != BreakerOpen {
+		t.Fatalf("got state %v, want failed probe to reopen breaker", b.state)
+	}
+}
@@ -0,0 +1,71 @@
+package gows
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// bufferedConn 带写缓冲的底层连接, 写入的帧先进入缓冲区, Flush 或缓冲区写满时才写入连接
+type bufferedConn struct {
+	net.Conn
+	// mutex 保护 w, 数据帧、控制帧与 Flush 可能来自不同协程
+	mutex sync.Mutex
+	// w 写缓冲
+	w *bufio.Writer
+}
+
+// Write 写入缓冲区
+func (b *bufferedConn) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.w.Write(p)
+}
+
+// Flush 将缓冲区写入连接
+func (b *bufferedConn) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.w.Flush()
+}
+
+// bufferedHijacker 在接管 http 连接时为其加上写缓冲
+type bufferedHijacker struct {
+	http.ResponseWriter
+	// size 写缓冲区大小
+	size int
+	// conn 接管后的连接
+	conn *bufferedConn
+}
+
+// Hijack 接管 http 连接
+func (h *bufferedHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := h.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gows: response does not implement http.Hijacker")
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.conn = &bufferedConn{Conn: conn, w: bufio.NewWriterSize(conn, h.size)}
+	return h.conn, brw, nil
+}
+
+// Flush 缓冲写入模式下将已写入缓冲区的消息发送到底层连接, 未开启缓冲写入时直接返回.
+// 写协程仍按顺序将写队列中的消息写入缓冲区, Flush 决定它们何时以尽量少的系统调用发出
+func (c *Connection) Flush() error {
+	if c.buffered == nil {
+		return nil
+	}
+	return c.buffered.Flush()
+}
+
+// flushControl 缓冲写入模式下立即发送控制帧, 避免 ping/pong 与关闭帧被延迟
+func (c *Connection) flushControl() {
+	if c.buffered != nil {
+		_ = c.buffered.Flush()
+	}
+}
@@ -0,0 +1,44 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferedWrites(t *testing.T) {
+	flush := make(chan struct{})
+	client := newTestServer(t, WithBufferedWrites(0), func(conn *Connection) {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("a")})
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("b")})
+		<-flush
+		_ = conn.Flush()
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	received := make(chan string, 2)
+	go func() {
+		for {
+			_, data, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(data)
+		}
+	}()
+	select {
+	case got := <-received:
+		t.Fatalf("got %q before Flush", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(flush)
+	for _, want := range []string{"a", "b"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q not delivered after Flush", want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gows
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ChaosOptions 故障注入可选参数, 概率取值 0~1
+type ChaosOptions struct {
+	// Latency 每条消息注入的固定延迟
+	Latency time.Duration
+	// LatencyJitter 在固定延迟之上随机增加 0~LatencyJitter 的延迟
+	LatencyJitter time.Duration
+	// DropRate 丢弃消息的概率
+	DropRate float64
+	// DuplicateRate 重复投递消息的概率
+	DuplicateRate float64
+	// DisconnectRate 处理消息时直接断开底层连接的概率, 不发送关闭帧, 模拟网络中断
+	DisconnectRate float64
+	// Seed 随机数种子, 为 0 时使用当前时间, 固定种子可复现故障序列
+	Seed int64
+}
+
+// Chaos 故障注入拦截器, 按配置的概率注入延迟、丢包、重复帧与断线, 用于在上线前验证客户端的重连与去重逻辑.
+// Inbound 与 Outbound 分别作为入站与出站拦截器使用, 应放在拦截器链的最后. 切勿在生产环境开启
+type Chaos struct {
+	// opts 配置
+	opts ChaosOptions
+	// mutex 保护 rand
+	mutex sync.Mutex
+	// rand 随机数生成器
+	rand *rand.Rand
+}
+
+// NewChaos 新建 Chaos实例
+func NewChaos(opts *ChaosOptions) *Chaos {
+	o := *opts
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+	return &Chaos{opts: o, rand: rand.New(rand.NewSource(o.Seed))}
+}
+
+// Inbound 入站故障注入拦截器, 重复的消息将在原消息之前进入读队列
+func (ch *Chaos) Inbound(conn *Connection, msg *Message) (*Message, error) {
+	if !ch.inject(conn, msg) {
+		return nil, nil
+	}
+	if ch.hit(ch.opts.DuplicateRate) && !conn.deliver(msg.Clone()) {
+		return nil, ErrConnClose
+	}
+	return msg, nil
+}
+
+// Outbound 出站故障注入拦截器, 重复的消息将在原消息之前直接写入底层连接
+func (ch *Chaos) Outbound(conn *Connection, msg *Message) (*Message, error) {
+	if !ch.inject(conn, msg) {
+		return nil, nil
+	}
+	if ch.hit(ch.opts.DuplicateRate) {
+		if err := conn.conn.WriteMessage(msg.MessageType, msg.Data); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// inject 注入延迟与断线, 消息应被丢弃时返回 false
+func (ch *Chaos) inject(conn *Connection, msg *Message) bool {
+	if delay := ch.delay(); delay > 0 {
+		time.Sleep(delay)
+	}
+	if ch.hit(ch.opts.DisconnectRate) {
+		_ = conn.conn.UnderlyingConn().Close()
+		return false
+	}
+	return !ch.hit(ch.opts.DropRate)
+}
+
+// delay 计算本次注入的延迟
+func (ch *Chaos) delay() time.Duration {
+	d := ch.opts.Latency
+	if ch.opts.LatencyJitter > 0 {
+		ch.mutex.Lock()
+		d += time.Duration(ch.rand.Int63n(int64(ch.opts.LatencyJitter)))
+		ch.mutex.Unlock()
+	}
+	return d
+}
+
+// hit 按概率 p 判断是否命中
+func (ch *Chaos) hit(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	return ch.rand.Float64() < p
+}
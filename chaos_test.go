@@ -0,0 +1,30 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChaosOutbound(t *testing.T) {
+	chaos := NewChaos(&ChaosOptions{DuplicateRate: 1})
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.UseOutbound(chaos.Outbound)
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("tick")})
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < 2; i++ {
+		if _, data, err := client.ReadMessage(); err != nil || string(data) != "tick" {
+			t.Fatalf("frame %d: got %q, %v", i, data, err)
+		}
+	}
+}
+
+func TestChaosDrop(t *testing.T) {
+	chaos := NewChaos(&ChaosOptions{DropRate: 1})
+	msg, err := chaos.Inbound(NewConnection(), &Message{MessageType: TextMessage, Data: []byte("x")})
+	if msg != nil || err != nil {
+		t.Fatalf("got %v, %v, want message dropped", msg, err)
+	}
+}
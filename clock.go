@@ -0,0 +1,64 @@
+package gows
+
+import "time"
+
+// Clock 时钟接口, 心跳检测与主动 ping 等定时逻辑通过它获取时间, 测试中可替换为可控的时钟
+type Clock interface {
+	// Now 当前时间
+	Now() time.Time
+	// NewTimer 新建定时器
+	NewTimer(d time.Duration) Timer
+	// NewTicker 新建周期定时器
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer 定时器, 语义与 *time.Timer 一致
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker 周期定时器, 语义与 *time.Ticker 一致
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock 基于 time 包的时钟
+type realClock struct{}
+
+// Now 当前时间
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer 新建定时器
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// NewTicker 新建周期定时器
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTimer 包装 *time.Timer
+type realTimer struct {
+	*time.Timer
+}
+
+// C 定时器通道
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// realTicker 包装 *time.Ticker
+type realTicker struct {
+	*time.Ticker
+}
+
+// C 周期定时器通道
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,9 @@
+package gows
+
+// compress 判断消息是否压缩, 仅在协商了 permessage-deflate 时生效
+func (c *Connection) compress(msg *Message) bool {
+	if len(msg.Data) < c.compressionThreshold {
+		return false
+	}
+	return c.shouldCompress == nil || c.shouldCompress(msg)
+}
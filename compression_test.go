@@ -0,0 +1,134 @@
+package gows
+
+import (
+	"bytes"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithCompression(9))
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		msg, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		_ = conn.Write(msg)
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	dialer := websocket.Dialer{EnableCompression: true}
+	client, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Fatalf("compression not negotiated: %q", ext)
+	}
+	payload := strings.Repeat("compress me ", 100)
+	if err = client.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != payload {
+		t.Fatal("payload mismatch")
+	}
+	if _, err = New(WithCompression(10)); err == nil {
+		t.Fatal("want error for invalid compression level")
+	}
+}
+
+// recordConn 记录读取到的原始字节
+type recordConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+// Read 读取并记录
+func (c *recordConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.buf.Write(p[:n])
+	return n, err
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	for name, write := range map[string]func(conn *Connection, msg *Message) error{
+		"write": (*Connection).Write,
+		"prepared": func(conn *Connection, msg *Message) error {
+			pm, err := NewPreparedMessage(msg)
+			if err != nil {
+				return err
+			}
+			return conn.WritePrepared(pm)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testCompressionThreshold(t, write)
+		})
+	}
+}
+
+func testCompressionThreshold(t *testing.T, write func(conn *Connection, msg *Message) error) {
+	opts := &Options{
+		EnableCompression:    true,
+		CompressionThreshold: 16,
+		ShouldCompress: func(msg *Message) bool {
+			return msg.MessageType == TextMessage
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(opts)
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = write(conn, &Message{MessageType: TextMessage, Data: []byte("small")})
+		_ = write(conn, &Message{MessageType: BinaryMessage, Data: bytes.Repeat([]byte("b"), 32)})
+		_ = write(conn, &Message{MessageType: TextMessage, Data: bytes.Repeat([]byte("t"), 32)})
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	var raw *recordConn
+	dialer := websocket.Dialer{
+		EnableCompression: true,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			raw = &recordConn{Conn: conn}
+			return raw, err
+		},
+	}
+	ws, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < 3; i++ {
+		if _, _, err = ws.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 跳过握手响应后逐帧检查 RSV1 位, 帧长度均小于126, 第二个字节即为长度
+	frames := raw.buf.Bytes()
+	frames = frames[bytes.Index(frames, []byte("\r\n\r\n"))+4:]
+	for i, want := range []bool{false, false, true} {
+		if got := frames[0]&0x40 != 0; got != want {
+			t.Fatalf("frame %d: got compressed %v, want %v", i, got, want)
+		}
+		frames = frames[2+int(frames[1]&0x7f):]
+	}
+}
@@ -0,0 +1,141 @@
+package gows
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config 可从配置文件或环境变量加载的连接配置, 便于部署时无需重新编译即可调整队列大小与超时
+type Config struct {
+	// InChanSize 读队列大小
+	InChanSize int `json:"in_chan_size"`
+	// OutChanSize 写队列大小
+	OutChanSize int `json:"out_chan_size"`
+	// HeartbeatInterval 心跳检测间隔, 秒
+	HeartbeatInterval int `json:"heartbeat_interval"`
+	// PingInterval 服务端主动 ping 间隔, 秒, 为 0 时不主动 ping
+	PingInterval int `json:"ping_interval"`
+	// MaxMissedPongs 允许连续未收到 pong 的次数
+	MaxMissedPongs int `json:"max_missed_pongs"`
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		InChanSize:        DefaultInChanSize,
+		OutChanSize:       DefaultOutChanSize,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		MaxMissedPongs:    DefaultMaxMissedPongs,
+	}
+}
+
+// LoadFile 从 JSON 或 YAML 文件加载配置, 按扩展名 .json、.yaml、.yml 区分格式, 文件中未出现的字段保持原值.
+// 配置均为整数字段, YAML 仅支持顶层的 key: value 形式
+func (c *Config) LoadFile(path string) error {
+	var decode func(data []byte) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decode = func(data []byte) error {
+			return json.Unmarshal(data, c)
+		}
+	case ".yaml", ".yml":
+		decode = c.decodeYAML
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedConfig, ext)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = decode(data); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// decodeYAML 解析顶层 key: value 形式的 YAML, 忽略注释、空行与未知字段
+func (c *Config) decodeYAML(data []byte) error {
+	values := make(map[string]*int)
+	for _, f := range c.fields() {
+		values[f.name] = f.value
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || line[0] == ' ' || line[0] == '\t' {
+			return fmt.Errorf("%w: yaml line %d: %q", ErrUnsupportedConfig, i+1, line)
+		}
+		value, ok := values[strings.TrimSpace(kv[0])]
+		if !ok {
+			continue
+		}
+		raw := strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%w: yaml line %d: %q", ErrInvalidOption, i+1, line)
+		}
+		*value = n
+	}
+	return nil
+}
+
+// LoadEnv 从环境变量加载配置, 变量名为 prefix 加上字段的 json 名称的大写形式,
+// 如 prefix 为 GOWS 时 InChanSize 对应 GOWS_IN_CHAN_SIZE. 未设置的变量保持原值
+func (c *Config) LoadEnv(prefix string) error {
+	for _, f := range c.fields() {
+		key := strings.ToUpper(prefix + "_" + f.name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%w: %s=%q", ErrInvalidOption, key, value)
+		}
+		*f.value = n
+	}
+	return c.Validate()
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	return c.Options().validate()
+}
+
+// Options 转换为连接配置
+func (c *Config) Options() *Options {
+	return &Options{
+		InChanSize:        c.InChanSize,
+		OutChanSize:       c.OutChanSize,
+		HeartbeatInterval: c.HeartbeatInterval,
+		PingInterval:      c.PingInterval,
+		MaxMissedPongs:    c.MaxMissedPongs,
+	}
+}
+
+// fields 返回可从环境变量与 YAML 加载的字段
+func (c *Config) fields() []struct {
+	name  string
+	value *int
+} {
+	return []struct {
+		name  string
+		value *int
+	}{
+		{"in_chan_size", &c.InChanSize},
+		{"out_chan_size", &c.OutChanSize},
+		{"heartbeat_interval", &c.HeartbeatInterval},
+		{"ping_interval", &c.PingInterval},
+		{"max_missed_pongs", &c.MaxMissedPongs},
+	}
+}
@@ -0,0 +1,76 @@
+package gows
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gows.json")
+	if err := ioutil.WriteFile(path, []byte(`{"in_chan_size": 16, "ping_interval": 30}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Setenv("GOWS_TEST_OUT_CHAN_SIZE", "32")
+	defer os.Unsetenv("GOWS_TEST_OUT_CHAN_SIZE")
+	if err := cfg.LoadEnv("GOWS_TEST"); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		InChanSize:        16,
+		OutChanSize:       32,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		PingInterval:      30,
+		MaxMissedPongs:    DefaultMaxMissedPongs,
+	}
+	if *cfg != want {
+		t.Fatalf("got %+v, want %+v", *cfg, want)
+	}
+}
+
+func TestConfigLoadInvalid(t *testing.T) {
+	_ = os.Setenv("GOWS_TEST_IN_CHAN_SIZE", "-1")
+	defer os.Unsetenv("GOWS_TEST_IN_CHAN_SIZE")
+	if err := DefaultConfig().LoadEnv("GOWS_TEST"); err == nil {
+		t.Fatal("want error for negative InChanSize")
+	}
+	if err := DefaultConfig().LoadFile("gows.toml"); err == nil {
+		t.Fatal("want error for unsupported format")
+	}
+}
+
+func TestConfigLoadYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gows.yaml")
+	data := "# gows\nin_chan_size: 16\nping_interval: \"30\" # seconds\nunknown: x\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InChanSize != 16 || cfg.PingInterval != 30 || cfg.OutChanSize != DefaultOutChanSize {
+		t.Fatalf("got %+v", *cfg)
+	}
+	if err := ioutil.WriteFile(path, []byte("in_chan_size: many\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := DefaultConfig().LoadFile(path); err == nil {
+		t.Fatal("want error for non-integer value")
+	}
+}
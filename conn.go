@@ -1,11 +1,15 @@
 package gows
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,16 +25,103 @@ type Conn interface {
 	Write(msg *Message) (err error)
 }
 
+// pendingMessage 写队列中的消息
+type pendingMessage struct {
+	// msg 消息
+	msg *Message
+	// enqueuedAt 入队时间, 仅开启延迟统计时记录
+	enqueuedAt time.Time
+	// attempts 此前写入失败的次数
+	attempts int
+	// firstFailed 首次写入失败时间
+	firstFailed time.Time
+	// size 入队时预留的内存, 字节
+	size int
+	// prepared 预处理的帧, 非 nil 且没有出站拦截器时直接写入
+	prepared *websocket.PreparedMessage
+	// done 非 nil 时接收写入结果, 容量为1
+	done chan error
+	// ephemeral 是否为瞬时消息, 写入失败时直接丢弃
+	ephemeral bool
+	// deadline 发送截止时间, 入队时根据消息的 Deadline 或有效期按连接的时钟确定
+	deadline time.Time
+}
+
+// expired 判断消息在 now 时是否已过期
+func (p pendingMessage) expired(now time.Time) bool {
+	return !p.deadline.IsZero() && !now.Before(p.deadline)
+}
+
 // Message 定义了一个消息实体.
 type Message struct {
 	// MessageType The message types are defined in RFC 6455, section 11.8.
 	MessageType int
-	// Data 消息内容
+	// Data 消息内容. 零拷贝接收模式下仅在下一次 Receive 前有效, 需要保留时使用 Clone
 	Data []byte
+	// Deadline 发送截止时间, 超过后仍在写队列或重试队列中的消息将被丢弃, 零值表示不过期
+	Deadline time.Time
+	// OrderKey 顺序键, 如会话ID. 非空且连接配置了重试队列时, 同一顺序键的消息在前一条等待重试期间
+	// 不会被写入, 而是排在其后一起重试, 保证同一顺序键的消息按写入顺序送达
+	OrderKey string
+	// ttl 有效期, 由 WithTTL 设置, 写入连接时按连接的时钟换算为截止时间
+	ttl time.Duration
+}
+
+// Clone 深拷贝消息
+func (m *Message) Clone() *Message {
+	data := make([]byte, len(m.Data))
+	copy(data, m.Data)
+	return &Message{MessageType: m.MessageType, Data: data, Deadline: m.Deadline, OrderKey: m.OrderKey, ttl: m.ttl}
+}
+
+// withData 复制消息的截止时间、顺序键等元数据并替换消息内容, 供转换消息内容的拦截器使用
+func (m *Message) withData(data []byte) *Message {
+	out := *m
+	out.Data = data
+	return &out
+}
+
+// WithTTL 设置消息的有效期, 返回消息本身. 有效期从消息写入连接时起按连接的时钟计算,
+// 同一消息写入多个连接时各自计时; 同时设置了 Deadline 时以 Deadline 为准
+func (m *Message) WithTTL(ttl time.Duration) *Message {
+	m.ttl = ttl
+	return m
+}
+
+// deadline 计算消息在 now 时写入的发送截止时间, 零值表示不过期
+func (m *Message) deadline(now time.Time) time.Time {
+	if !m.Deadline.IsZero() || m.ttl == 0 {
+		return m.Deadline
+	}
+	return now.Add(m.ttl)
 }
 
 // Connection 维护的长连接.
 type Connection struct {
+	// heartbeatInterval 心跳检测间隔, 原子读写, 放在结构体开头保证64位对齐
+	heartbeatInterval int64
+	// lastHeartbeatTime 最近一次心跳时间, UnixNano, 原子读写
+	lastHeartbeatTime int64
+	// credits 流控模式下剩余可发送的消息数, 原子读写
+	credits int64
+	// dropped 被丢弃的消息数, 原子读写
+	dropped int64
+	// inHighWater 读队列消息数的历史最大值, 原子读写
+	inHighWater int64
+	// outHighWater 写队列消息数的历史最大值, 原子读写
+	outHighWater int64
+	// memory 读写队列中消息占用的内存, 字节, 原子读写
+	memory int64
+	// memoryBudget 内存预算, 字节, 0 表示不限制
+	memoryBudget int64
+	// connectedAt 协议升级完成时间, UnixNano, 原子读写
+	connectedAt int64
+	// closedAt 连接关闭时间, UnixNano, 原子读写
+	closedAt int64
+	// traffic 收发的帧数与字节数, 原子读写
+	traffic traffic
+	// aboveHighWatermark 写队列消息数是否越过高水位且尚未回落到低水位, 原子读写
+	aboveHighWatermark int32
 	// id 标识id
 	id string
 	// conn 底层长连接
@@ -38,82 +129,351 @@ type Connection struct {
 	// inChan 读队列
 	inChan chan *Message
 	// outChan 写队列
-	outChan chan *Message
+	outChan chan pendingMessage
+	// urgentChan 紧急消息写队列
+	urgentChan chan pendingMessage
+	// topics 按主题划分的写队列
+	topics *topicQueues
+	// ephemeral 瞬时消息槽
+	ephemeral *ephemeralSlots
 	// closeChan 关闭通知
 	closeChan chan struct{}
-	// heartbeatInterval 心跳检测间隔, 秒
-	heartbeatInterval int
-	// lastHeartbeatTime 最近一次心跳时间
-	lastHeartbeatTime time.Time
+	// heartbeatChan 心跳检测间隔变更通知
+	heartbeatChan chan struct{}
 	// mutex 保护 closeChan 只被执行一次
 	mutex sync.Mutex
 	// isClosed closeChan状态
 	isClosed bool
+	// closeReason 关闭原因
+	closeReason error
+	// onOpen 连接开启后的回调
+	onOpen func(conn *Connection)
+	// onClose 连接关闭后的回调
+	onClose func(conn *Connection, reason error)
+	// onPanic 发生 panic 时的回调
+	onPanic func(conn *Connection, recovered interface{}, stack []byte)
+	// onPing 收到 ping 控制帧时的回调
+	onPing func(conn *Connection, data []byte)
+	// onPong 收到 pong 控制帧时的回调
+	onPong func(conn *Connection, data []byte)
+	// autoPong 文本心跳自动应答配置
+	autoPong *AutoPongOptions
+	// pingInterval 服务端主动 ping 间隔, 秒
+	pingInterval int
+	// maxMissedPongs 允许连续未收到 pong 的次数
+	maxMissedPongs int32
+	// missedPongs 当前连续未收到 pong 的次数
+	missedPongs int32
+	// logger 日志
+	logger Logger
+	// subprotocols 服务端支持的子协议
+	subprotocols []string
+	// version 协商出的协议版本
+	version string
+	// clock 时钟
+	clock Clock
+	// readBufferSize 读缓冲区大小
+	readBufferSize int
+	// writeBufferSize 写缓冲区大小
+	writeBufferSize int
+	// writeBufferPool 写缓冲区池
+	writeBufferPool BufferPool
+	// enableCompression 是否协商 permessage-deflate 压缩
+	enableCompression bool
+	// compressionLevel 压缩级别, 0 表示使用默认级别
+	compressionLevel int
+	// compressionThreshold 小于该字节数的消息不压缩
+	compressionThreshold int
+	// shouldCompress 逐条决定消息是否压缩
+	shouldCompress func(msg *Message) bool
+	// flowControl 是否开启基于额度的流控
+	flowControl bool
+	// creditChan 收到额度通知
+	creditChan chan struct{}
+	// zeroCopy 是否开启零拷贝接收
+	zeroCopy bool
+	// readBuf 零拷贝接收模式下复用的读缓冲区
+	readBuf bytes.Buffer
+	// releaseChan 零拷贝接收模式下通知读协程上一条消息已释放
+	releaseChan chan struct{}
+	// received 零拷贝接收模式下是否有已交付但未释放的消息, 原子读写
+	received int32
+	// pauseMutex 保护 resumeChan
+	pauseMutex sync.Mutex
+	// resumeChan 暂停读取时非 nil, 恢复读取时关闭
+	resumeChan chan struct{}
+	// retry 写入失败消息的重试队列
+	retry *RetryQueue
+	// retryChan 重试写队列, 开启连接时按等待重试的消息数创建, 优先于 outChan 写入
+	retryChan chan pendingMessage
+	// memoryPolicy 超出内存预算时的处理策略
+	memoryPolicy MemoryPolicy
+	// memoryAccountant 全局内存预算
+	memoryAccountant *MemoryAccountant
+	// tlsState TLS 连接状态, 非 TLS 连接时为 nil
+	tlsState *tls.ConnectionState
+	// certUser 将客户端证书映射为用户ID
+	certUser func(cert *x509.Certificate) string
+	// authenticator 校验认证帧, 为 nil 时不等待认证帧
+	authenticator Authenticator
+	// authTimeout 等待认证帧的超时时间
+	authTimeout time.Duration
+	// highWatermark 写队列高水位, 0 表示不开启水位回调
+	highWatermark int
+	// lowWatermark 写队列低水位
+	lowWatermark int
+	// onHighWatermark 写队列消息数越过高水位时的回调
+	onHighWatermark func(conn *Connection, depth int)
+	// onLowWatermark 写队列消息数回落到低水位时的回调
+	onLowWatermark func(conn *Connection, depth int)
+	// deadLetter 消息被丢弃时的回调
+	deadLetter DeadLetterHandler
+	// bufferedWrites 是否开启缓冲写入
+	bufferedWrites bool
+	// flushInterval 缓冲写入模式下自动 Flush 的间隔, 0 表示仅手动 Flush
+	flushInterval time.Duration
+	// buffered 缓冲写入模式下带写缓冲的底层连接
+	buffered *bufferedConn
+	// socketHook 连接升级后对底层连接进行设置
+	socketHook SocketHook
+	// batchMutex 保护 batch
+	batchMutex sync.Mutex
+	// batch 待合并的信封, 未开启批量帧时为 nil
+	batch *batcher
+	// heartbeatJitter 心跳检测与主动 ping 间隔的随机抖动比例
+	heartbeatJitter float64
+	// latency 延迟统计, 未开启时为 nil
+	latency *LatencyStats
+	// userID 绑定的用户ID
+	userID atomic.Value
+	// interceptorMutex 保护拦截器链
+	interceptorMutex sync.RWMutex
+	// inboundInterceptors 入站拦截器链
+	inboundInterceptors []Interceptor
+	// outboundInterceptors 出站拦截器链
+	outboundInterceptors []Interceptor
 }
 
-// Options 可选参数
-type Options struct {
-	// InChanSize 读队列大小, 默认1024
-	InChanSize int
-	// OutChanSize 写队列大小, 默认1024
-	OutChanSize int
-	// HeartbeatInterval 心跳检测间隔, 当心跳间隔大于这个时间连接将断开, 默认300s
-	HeartbeatInterval int
+// NewConnection 新建 Connection实例. 非法的配置值将被忽略并使用默认值.
+func NewConnection(opts ...Option) *Connection {
+	return newConnection(buildOptions(opts))
 }
 
-// NewConnection 新建 Connection实例.
-func NewConnection(opts ...*Options) *Connection {
+// New 新建 Connection实例, 与 NewConnection 不同的是配置值非法时返回 ErrInvalidOption.
+func New(opts ...Option) (*Connection, error) {
+	o := buildOptions(opts)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return newConnection(o), nil
+}
+
+// newConnection 根据配置新建 Connection实例, 未设置或非法的配置值使用默认值
+func newConnection(opt *Options) *Connection {
 	inChanSize, outChanSize := DefaultInChanSize, DefaultOutChanSize
 	heartbeatInterval := DefaultHeartbeatInterval
-	if len(opts) > 0 {
-		opt := opts[0]
-		if opt.InChanSize > 0 {
-			inChanSize = opt.InChanSize
-		}
-		if opt.OutChanSize > 0 {
-			outChanSize = opt.OutChanSize
-		}
-		if opt.HeartbeatInterval > 0 {
-			heartbeatInterval = opt.HeartbeatInterval
-		}
+	pingInterval, maxMissedPongs := 0, DefaultMaxMissedPongs
+	var logger Logger = nopLogger{}
+	var clock Clock = realClock{}
+	if opt.InChanSize > 0 {
+		inChanSize = opt.InChanSize
+	}
+	if opt.OutChanSize > 0 {
+		outChanSize = opt.OutChanSize
+	}
+	if opt.HeartbeatInterval > 0 {
+		heartbeatInterval = opt.HeartbeatInterval
+	}
+	if opt.PingInterval > 0 {
+		pingInterval = opt.PingInterval
+	}
+	if opt.MaxMissedPongs > 0 {
+		maxMissedPongs = opt.MaxMissedPongs
+	}
+	if opt.Logger != nil {
+		logger = opt.Logger
+	}
+	if opt.Clock != nil {
+		clock = opt.Clock
+	}
+	id := uuid.NewString
+	if opt.IDGenerator != nil {
+		id = opt.IDGenerator
+	}
+	if opt.RetryQueue != nil {
+		opt.RetryQueue.setClock(clock)
+	}
+	lowWatermark := opt.LowWatermark
+	if lowWatermark <= 0 {
+		lowWatermark = opt.HighWatermark / 2
 	}
 	return &Connection{
-		id:                uuid.NewString(),
-		conn:              nil,
-		inChan:            make(chan *Message, inChanSize),
-		outChan:           make(chan *Message, outChanSize),
-		closeChan:         make(chan struct{}, 1),
-		heartbeatInterval: heartbeatInterval,
-		lastHeartbeatTime: time.Now(),
+		id:                   id(),
+		conn:                 nil,
+		inChan:               make(chan *Message, inChanSize),
+		outChan:              make(chan pendingMessage, outChanSize),
+		urgentChan:           make(chan pendingMessage, DefaultUrgentChanSize),
+		topics:               newTopicQueues(outChanSize),
+		ephemeral:            newEphemeralSlots(),
+		closeChan:            make(chan struct{}, 1),
+		heartbeatChan:        make(chan struct{}, 1),
+		heartbeatInterval:    int64(time.Duration(heartbeatInterval) * time.Second),
+		lastHeartbeatTime:    clock.Now().UnixNano(),
+		onPing:               opt.OnPing,
+		onPong:               opt.OnPong,
+		autoPong:             opt.AutoPong,
+		pingInterval:         pingInterval,
+		maxMissedPongs:       int32(maxMissedPongs),
+		logger:               logger,
+		subprotocols:         opt.Subprotocols,
+		onOpen:               opt.OnOpen,
+		onClose:              opt.OnClose,
+		onPanic:              opt.OnPanic,
+		latency:              newLatencyStats(opt.TrackLatency),
+		clock:                clock,
+		heartbeatJitter:      opt.HeartbeatJitter,
+		retry:                opt.RetryQueue,
+		readBufferSize:       opt.ReadBufferSize,
+		writeBufferSize:      opt.WriteBufferSize,
+		writeBufferPool:      opt.WriteBufferPool,
+		enableCompression:    opt.EnableCompression,
+		compressionLevel:     opt.CompressionLevel,
+		compressionThreshold: opt.CompressionThreshold,
+		shouldCompress:       opt.ShouldCompress,
+		flowControl:          opt.FlowControl,
+		credits:              int64(opt.InitialCredits),
+		creditChan:           make(chan struct{}, 1),
+		zeroCopy:             opt.ZeroCopy,
+		releaseChan:          make(chan struct{}, 1),
+		memoryBudget:         int64(opt.MemoryBudget),
+		memoryPolicy:         opt.MemoryPolicy,
+		memoryAccountant:     opt.MemoryAccountant,
+		certUser:             opt.CertUser,
+		batch:                newBatcher(opt.Batch),
+		authenticator:        opt.Authenticate,
+		authTimeout:          opt.AuthTimeout,
+		deadLetter:           opt.DeadLetter,
+		inboundInterceptors:  append([]Interceptor(nil), opt.Inbound...),
+		outboundInterceptors: append([]Interceptor(nil), opt.Outbound...),
+		bufferedWrites:       opt.BufferedWrites,
+		flushInterval:        opt.FlushInterval,
+		socketHook:           opt.SocketHook,
+		highWatermark:        opt.HighWatermark,
+		lowWatermark:         lowWatermark,
+		onHighWatermark:      opt.OnHighWatermark,
+		onLowWatermark:       opt.OnLowWatermark,
 	}
 }
 
 // Close 关闭连接
 func (c *Connection) Close() error {
-	return c.close()
+	return c.close(nil)
 }
 
-// close 关闭连接
-func (c *Connection) close() error {
-	_ = c.conn.Close()
+// close 关闭连接, reason 为关闭原因, 应用主动关闭时为 nil. 仅首次关闭时回调 onClose
+func (c *Connection) close(reason error) error {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	if !c.isClosed {
-		close(c.closeChan)
-		c.isClosed = true
+	if c.isClosed {
+		c.mutex.Unlock()
+		return nil
+	}
+	close(c.closeChan)
+	c.isClosed = true
+	c.closeReason = reason
+	c.mutex.Unlock()
+	// 先记录关闭原因再关闭底层连接, 避免读协程因连接关闭产生的错误覆盖原因
+	if c.conn != nil {
+		c.flushControl()
+		_ = c.conn.Close()
+	}
+	c.drainIn()
+	c.recordSession()
+	if c.onClose != nil {
+		c.protect(func() {
+			c.onClose(c, reason)
+		})
 	}
 	return nil
 }
 
+// closed 判断连接是否已关闭
+func (c *Connection) closed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.isClosed
+}
+
 // Open 开启连接
 func (c *Connection) Open(w http.ResponseWriter, r *http.Request) error {
-	conn, err := upgrade.Upgrade(w, r, nil)
+	if c.memoryAccountant != nil && c.memoryAccountant.Exceeded() {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return ErrMemoryBudget
+	}
+	upgrader := upgrade
+	upgrader.Subprotocols = c.subprotocols
+	upgrader.EnableCompression = c.enableCompression
+	upgrader.ReadBufferSize = c.readBufferSize
+	upgrader.WriteBufferSize = c.writeBufferSize
+	if c.writeBufferPool != nil {
+		upgrader.WriteBufferPool = c.writeBufferPool
+	}
+	var hijacker *bufferedHijacker
+	if c.bufferedWrites {
+		hijacker = &bufferedHijacker{ResponseWriter: w, size: c.writeBufferSize}
+		if hijacker.size <= 0 {
+			hijacker.size = DefaultWriteBufferSize
+		}
+		w = hijacker
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return err
 	}
 	c.conn = conn
+	atomic.StoreInt64(&c.connectedAt, c.clock.Now().UnixNano())
+	if hijacker != nil {
+		// 握手响应同样写入了缓冲区, 需要立即发送
+		c.buffered = hijacker.conn
+		if err = c.buffered.Flush(); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+	if c.socketHook != nil {
+		if err = c.socketHook(c.netConn()); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+	c.tlsState = r.TLS
+	if cert := c.PeerCertificate(); cert != nil && c.certUser != nil {
+		c.SetUserID(c.certUser(cert))
+	}
+	if c.compressionLevel != 0 {
+		if err = conn.SetCompressionLevel(c.compressionLevel); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+	c.setControlHandlers()
+	if c.retry != nil {
+		c.retryChan = make(chan pendingMessage, c.retry.Len())
+	}
 	go c.readLoop()
 	go c.writeLoop()
+	if c.authenticator != nil {
+		if err = c.authenticate(); err != nil {
+			return err
+		}
+	}
+	if c.retry != nil {
+		c.retry.resend(c)
+	}
+	if c.onOpen != nil {
+		c.protect(func() {
+			c.onOpen(c)
+		})
+	}
 	return nil
 }
 
@@ -126,58 +486,285 @@ var upgrade = websocket.Upgrader{
 
 // readLoop 监听客户端消息
 func (c *Connection) readLoop() {
+	defer c.recoverPanic()
 	for {
-		msgType, data, err := c.conn.ReadMessage()
-		if err != nil {
-			_ = c.close()
+		if !c.waitReadResume() {
 			goto EXIT
 		}
-		select {
-		case c.inChan <- &Message{
-			MessageType: msgType,
-			Data:        data,
-		}:
-		case <-c.closeChan:
+		msgType, data, err := c.readMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.logger.Printf("gows: connection %s read error: %v", c.id, err)
+			}
+			_ = c.close(err)
 			goto EXIT
 		}
+		c.countIn(len(data))
+		msgs := []*Message{{MessageType: msgType, Data: data}}
+		// 批量帧先拆分, 其中每条消息分别经过入站拦截器
+		if c.batch != nil {
+			if inner, ok := c.unbatch(msgs[0]); ok {
+				msgs = inner
+			}
+		}
+		for _, msg := range msgs {
+			if !c.accept(msg) {
+				goto EXIT
+			}
+		}
 	}
 EXIT:
 	// 确保连接被关闭
 	return
 }
 
+// accept 入站消息经过拦截器后交付, 连接关闭时返回 false
+func (c *Connection) accept(raw *Message) bool {
+	msg, err := c.intercept(c.inbound(), raw)
+	if err != nil {
+		c.logger.Printf("gows: connection %s inbound message dropped: %v", c.id, err)
+		c.drop(raw, false, err)
+		return true
+	}
+	if msg == nil {
+		return true
+	}
+	return c.deliver(msg)
+}
+
+// deliver 处理流控额度帧与文本心跳后将消息放入读队列, 连接关闭时返回 false
+func (c *Connection) deliver(msg *Message) bool {
+	if c.flowControl && c.handleCredit(msg) {
+		return true
+	}
+	if c.autoPong != nil && c.autoPong.match(msg.Data) {
+		c.KeepHeartbeat()
+		if c.Write(&Message{MessageType: msg.MessageType, Data: []byte(c.autoPong.Reply)}) != nil {
+			return false
+		}
+		if c.autoPong.Hide {
+			return true
+		}
+	}
+	if err := c.reserveMemory(len(msg.Data)); err != nil {
+		c.logger.Printf("gows: connection %s inbound message dropped: %v", c.id, err)
+		c.drop(msg, false, err)
+		return true
+	}
+	select {
+	case c.inChan <- msg:
+		updateHighWater(&c.inHighWater, len(c.inChan))
+	case <-c.closeChan:
+		c.releaseMemory(len(msg.Data))
+		return false
+	}
+	// 关闭与写入读队列同时发生时消息可能在 close 清空读队列之后写入, 需再次清空以释放预留的内存
+	if c.closed() {
+		c.drainIn()
+		return false
+	}
+	return !c.zeroCopy || c.waitRelease()
+}
+
 // writeLoop 向连接写入数据
 func (c *Connection) writeLoop() {
-	timer := time.NewTimer(time.Duration(c.heartbeatInterval) * time.Second)
+	defer c.recoverPanic()
+	timer := c.clock.NewTimer(c.jitter(c.getHeartbeatInterval()))
 	defer timer.Stop()
+	pingInterval := time.Duration(c.pingInterval) * time.Second
+	var pingTimer Timer
+	var pingC <-chan time.Time
+	if pingInterval > 0 {
+		pingTimer = c.clock.NewTimer(c.jitter(pingInterval))
+		defer pingTimer.Stop()
+		pingC = pingTimer.C()
+	}
+	var flushC <-chan time.Time
+	if c.buffered != nil && c.flushInterval > 0 {
+		flushTicker := c.clock.NewTicker(c.flushInterval)
+		defer flushTicker.Stop()
+		flushC = flushTicker.C()
+	}
 	for {
+		// 开启流控且额度耗尽时暂停消费写队列, 消息保留在队列中等待对端授予额度
+		retryChan, outChan, topicChan, ephemeralChan := c.retryChan, c.outChan, c.topics.notify, c.ephemeral.notify
+		if c.flowControl && atomic.LoadInt64(&c.credits) <= 0 {
+			retryChan, outChan, topicChan, ephemeralChan = nil, nil, nil, nil
+		}
+		// 紧急消息优先于写队列中的消息, 重试的消息优先于之后写入的消息
+		select {
+		case pending := <-c.urgentChan:
+			c.writeMessage(pending)
+			continue
+		default:
+		}
 		select {
-		case msg := <-c.outChan:
-			_ = c.conn.WriteMessage(msg.MessageType, msg.Data)
-		case <-timer.C:
+		case pending := <-retryChan:
+			if c.flowControl {
+				atomic.AddInt64(&c.credits, -1)
+			}
+			c.writeMessage(pending)
+			continue
+		default:
+		}
+		select {
+		case pending := <-c.urgentChan:
+			c.writeMessage(pending)
+		case pending := <-retryChan:
+			if c.flowControl {
+				atomic.AddInt64(&c.credits, -1)
+			}
+			c.writeMessage(pending)
+		case pending := <-outChan:
+			if c.flowControl {
+				atomic.AddInt64(&c.credits, -1)
+			}
+			c.writeMessage(pending)
+		case <-topicChan:
+			if pending, ok := c.topics.pop(); ok {
+				if c.flowControl {
+					atomic.AddInt64(&c.credits, -1)
+				}
+				c.writeMessage(pending)
+			}
+		case <-ephemeralChan:
+			if pending, ok := c.ephemeral.pop(); ok {
+				if c.flowControl {
+					atomic.AddInt64(&c.credits, -1)
+				}
+				_ = c.write(pending)
+			}
+		case <-c.creditChan:
+		case <-timer.C():
 			if !c.isAlive() {
-				_ = c.close()
+				_ = c.close(ErrHeartbeatTimeout)
+				goto EXIT
+			}
+			timer.Reset(c.jitter(c.getHeartbeatInterval()))
+		case <-c.heartbeatChan:
+			if !timer.Stop() {
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+			timer.Reset(c.jitter(c.getHeartbeatInterval()))
+		case <-flushC:
+			if err := c.Flush(); err != nil {
+				c.logger.Printf("gows: connection %s flush error: %v", c.id, err)
+			}
+		case <-pingC:
+			if !c.ping() {
+				_ = c.close(ErrPongTimeout)
 				goto EXIT
 			}
-			timer.Reset(time.Duration(c.heartbeatInterval) * time.Second)
+			pingTimer.Reset(c.jitter(pingInterval))
 		case <-c.closeChan:
 			goto EXIT
 		}
 	}
 EXIT:
 	// 确保连接被关闭
+	c.drainOut()
 	return
 }
 
+// writeMessage 经过出站拦截器后将消息写入底层连接, 并通知等待写入结果的 WriteSync
+func (c *Connection) writeMessage(pending pendingMessage) {
+	err := c.write(pending)
+	if pending.done != nil {
+		pending.done <- err
+	}
+}
+
+// write 经过出站拦截器后将消息写入底层连接, 被拦截器过滤的消息视为写入成功
+func (c *Connection) write(pending pendingMessage) error {
+	defer c.releaseMemory(pending.size)
+	c.dequeued()
+	if c.latency != nil {
+		dequeuedAt := time.Now()
+		if !pending.enqueuedAt.IsZero() {
+			c.latency.observeQueueWait(dequeuedAt.Sub(pending.enqueuedAt))
+		}
+		defer func() {
+			c.latency.observeWrite(time.Since(dequeuedAt))
+		}()
+	}
+	if pending.expired(c.clock.Now()) {
+		if !pending.ephemeral {
+			c.drop(pending.msg, true, ErrMessageExpired)
+		}
+		return ErrMessageExpired
+	}
+	// 瞬时消息不进入重试队列, 因此也不按顺序键暂存
+	if c.retry != nil && !pending.ephemeral && pending.msg.OrderKey != "" && c.retry.holding(pending.msg.OrderKey) {
+		c.retry.hold(pending)
+		return ErrOrderHeld
+	}
+	var err error
+	size := len(pending.msg.Data)
+	if pending.prepared != nil && len(c.outbound()) == 0 {
+		// 预处理的帧已编码完成, 没有出站拦截器时直接写入, 压缩决策与普通消息一致
+		if c.enableCompression {
+			c.conn.EnableWriteCompression(c.compress(pending.msg))
+		}
+		err = c.conn.WritePreparedMessage(pending.prepared)
+	} else {
+		var msg *Message
+		if msg, err = c.intercept(c.outbound(), pending.msg); err != nil {
+			c.logger.Printf("gows: connection %s outbound message dropped: %v", c.id, err)
+			if !pending.ephemeral {
+				c.drop(pending.msg, true, err)
+			}
+			return err
+		}
+		if msg == nil {
+			return nil
+		}
+		if c.enableCompression {
+			c.conn.EnableWriteCompression(c.compress(msg))
+		}
+		size = len(msg.Data)
+		err = c.conn.WriteMessage(msg.MessageType, msg.Data)
+	}
+	if err != nil {
+		c.logger.Printf("gows: connection %s write error: %v", c.id, err)
+		if pending.ephemeral {
+			return err
+		}
+		if c.retry != nil {
+			c.retry.push(pending, err)
+		} else {
+			c.drop(pending.msg, true, err)
+		}
+		return err
+	}
+	c.countOut(size)
+	if c.retry != nil {
+		c.retry.delivered(pending)
+	}
+	return nil
+}
+
 // isAlive 判断连接是否活跃
 func (c *Connection) isAlive() bool {
-	return time.Since(c.lastHeartbeatTime) <= time.Duration(c.heartbeatInterval)*time.Second
+	return c.clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&c.lastHeartbeatTime))) <= c.getHeartbeatInterval()
 }
 
 // Receive 接收数据
 func (c *Connection) Receive() (msg *Message, err error) {
+	return c.receive(nil)
+}
+
+// receive 接收数据, timeout 触发时返回 ErrReceiveTimeout
+func (c *Connection) receive(timeout <-chan time.Time) (msg *Message, err error) {
+	c.releaseReceived()
 	select {
 	case msg = <-c.inChan:
+		c.releaseMemory(len(msg.Data))
+		c.markReceived()
+	case <-timeout:
+		err = ErrReceiveTimeout
 	case <-c.closeChan:
 		err = ErrConnClose
 	}
@@ -186,25 +773,92 @@ func (c *Connection) Receive() (msg *Message, err error) {
 
 // Write 写入数据
 func (c *Connection) Write(msg *Message) (err error) {
+	return c.enqueue(c.outChan, pendingMessage{msg: msg, size: len(msg.Data)})
+}
+
+// WriteUrgent 写入紧急消息, 如 "服务即将重启" 等控制类消息. 紧急消息使用独立的小容量队列,
+// 优先于 Write 写入的消息发送且不受流控额度与内存预算限制, 不会被积压的普通消息阻塞
+func (c *Connection) WriteUrgent(msg *Message) error {
+	return c.enqueue(c.urgentChan, pendingMessage{msg: msg, size: len(msg.Data)})
+}
+
+// enqueue 将消息放入写队列 queue
+func (c *Connection) enqueue(queue chan pendingMessage, pending pendingMessage) (err error) {
+	if c.latency != nil {
+		pending.enqueuedAt = time.Now()
+	}
+	// 重试的消息保留首次写入时的截止时间
+	if pending.deadline.IsZero() {
+		pending.deadline = pending.msg.deadline(c.clock.Now())
+	}
+	// 紧急消息不受内存预算限制, 超出预算时只丢弃普通消息
+	if queue == c.urgentChan {
+		c.forceMemory(pending.size)
+	} else if err = c.reserveMemory(pending.size); err != nil {
+		c.reject(pending, err)
+		return
+	}
+	// 先检查连接是否已关闭, 避免关闭后写队列仍有空位时消息被静默接收
 	select {
-	case c.outChan <- msg:
 	case <-c.closeChan:
-		err = ErrConnClose
+	default:
+		select {
+		case queue <- pending:
+			c.enqueued()
+			return
+		case <-c.closeChan:
+		}
 	}
+	c.releaseMemory(pending.size)
+	err = ErrConnClose
+	c.reject(pending, err)
 	return
 }
 
+// reject 处理未能入队的消息, 来自重试队列的消息放回重试队列且不计入尝试次数, 其余丢弃
+func (c *Connection) reject(pending pendingMessage, err error) {
+	if c.retry != nil && !pending.firstFailed.IsZero() {
+		pending.attempts--
+		c.retry.push(pending, err)
+		return
+	}
+	c.drop(pending.msg, true, err)
+}
+
 // GetConnID 获取连接ID
 func (c *Connection) GetConnID() string {
 	return c.id
 }
 
+// SetUserID 绑定用户ID
+func (c *Connection) SetUserID(userID string) {
+	c.userID.Store(userID)
+}
+
+// GetUserID 获取绑定的用户ID, 未绑定时为空
+func (c *Connection) GetUserID() string {
+	userID, _ := c.userID.Load().(string)
+	return userID
+}
+
 // GetRemoteAddr 获取远程地址
 func (c *Connection) GetRemoteAddr() net.Addr {
 	return c.conn.RemoteAddr()
 }
 
+// CloseReason 获取连接关闭原因, 连接未关闭或由应用主动关闭时为 nil
+func (c *Connection) CloseReason() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.closeReason
+}
+
+// Subprotocol 获取握手协商出的子协议
+func (c *Connection) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
 // KeepHeartbeat 保持心跳
 func (c *Connection) KeepHeartbeat() {
-	c.lastHeartbeatTime = time.Now()
+	atomic.StoreInt64(&c.lastHeartbeatTime, c.clock.Now().UnixNano())
 }
@@ -2,7 +2,10 @@ package gows
 
 import (
 	"fmt"
+	"github.com/gorilla/websocket"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -37,3 +40,36 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// testClient 测试客户端连接, Close 时同时关闭测试服务
+type testClient struct {
+	*websocket.Conn
+	// srv 测试服务
+	srv *httptest.Server
+}
+
+// Close 关闭客户端连接与测试服务
+func (c *testClient) Close() error {
+	err := c.Conn.Close()
+	c.srv.Close()
+	return err
+}
+
+// newTestServer 启动一个测试服务, 对每个请求使用 opts 新建连接并交给 handler 处理, 返回客户端连接, 调用方需 Close
+func newTestServer(t testing.TB, opts Option, handler func(conn *Connection)) *testClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(opts)
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return &testClient{Conn: client, srv: srv}
+}
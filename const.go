@@ -5,6 +5,60 @@ import "errors"
 var (
 	// ErrConnClose 连接已关闭
 	ErrConnClose = errors.New("connection already closed")
+	// ErrInvalidOption 配置值非法
+	ErrInvalidOption = errors.New("invalid option")
+	// ErrUnsupportedConfig 不支持的配置文件格式
+	ErrUnsupportedConfig = errors.New("unsupported config format")
+	// ErrDecrypt 消息解密失败
+	ErrDecrypt = errors.New("message decryption failed")
+	// ErrInvalidSignature 消息签名校验失败
+	ErrInvalidSignature = errors.New("invalid message signature")
+	// ErrReplayed 消息超出防重放时间窗口或随机数重复
+	ErrReplayed = errors.New("message replayed")
+	// ErrInvalidEnvelope 消息信封格式错误
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	// ErrSchemaValidation 消息体不符合 Schema
+	ErrSchemaValidation = errors.New("schema validation failed")
+	// ErrUnsupportedVersion 不支持的协议版本
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	// ErrHeartbeatTimeout 心跳超时
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+	// ErrPongTimeout 连续未收到 pong
+	ErrPongTimeout = errors.New("pong timeout")
+	// ErrWorkerPoolClosed 工作池已关闭
+	ErrWorkerPoolClosed = errors.New("worker pool already closed")
+	// ErrPanic 读写协程或回调发生 panic
+	ErrPanic = errors.New("panic recovered")
+	// ErrRetryExhausted 消息重试次数或保留时间耗尽
+	ErrRetryExhausted = errors.New("message retry exhausted")
+	// ErrOrderHeld 消息因同一顺序键的消息等待重试而暂存到重试队列
+	ErrOrderHeld = errors.New("message held behind earlier message with the same order key")
+	// ErrBreakerOpen 熔断器已打开
+	ErrBreakerOpen = errors.New("circuit breaker open")
+	// ErrMemoryBudget 超出内存预算
+	ErrMemoryBudget = errors.New("memory budget exceeded")
+	// ErrForbidden 无权限
+	ErrForbidden = errors.New("forbidden")
+	// ErrTokenInactive 令牌无效、已过期或已被吊销
+	ErrTokenInactive = errors.New("token inactive")
+	// ErrInvalidAPIKey API key 缺失或无效
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrRateLimited 超出速率限制
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAuthTimeout 超时未收到认证帧
+	ErrAuthTimeout = errors.New("authentication timeout")
+	// ErrMessageExpired 消息已超过发送截止时间
+	ErrMessageExpired = errors.New("message expired")
+	// ErrTopicQueueFull 主题写队列已满
+	ErrTopicQueueFull = errors.New("topic queue full")
+	// ErrDraining 服务正在下线
+	ErrDraining = errors.New("server draining")
+	// ErrAuditQueueFull 审计事件待发送队列已满
+	ErrAuditQueueFull = errors.New("audit queue full")
+	// ErrAuditSinkClosed 审计输出已关闭
+	ErrAuditSinkClosed = errors.New("audit sink already closed")
+	// ErrReceiveTimeout 接收数据超时, 实现了 net.Error 且 Timeout() 为 true
+	ErrReceiveTimeout error = timeoutError{}
 )
 
 // The message types are defined in RFC 6455, section 11.8.
@@ -37,6 +91,15 @@ const (
 	// DefaultOutChanSize 默认写队列大小
 	DefaultOutChanSize = 1024
 
+	// DefaultWriteBufferSize 默认写缓冲区大小, 与 gorilla/websocket 一致
+	DefaultWriteBufferSize = 4096
+
+	// DefaultUrgentChanSize 默认紧急消息写队列大小
+	DefaultUrgentChanSize = 16
+
 	// DefaultHeartbeatInterval 默认心跳检测间隔
 	DefaultHeartbeatInterval = 300
+
+	// DefaultMaxMissedPongs 默认允许连续未收到 pong 的次数
+	DefaultMaxMissedPongs = 3
 )
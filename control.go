@@ -0,0 +1,58 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// controlWriteWait 写控制帧的超时时间
+const controlWriteWait = time.Second
+
+// setControlHandlers 注册 ping/pong 控制帧处理, 在回调用户钩子的同时保留默认的 pong 回复行为
+func (c *Connection) setControlHandlers() {
+	c.conn.SetPingHandler(func(appData string) error {
+		if c.onPing != nil {
+			c.onPing(c, []byte(appData))
+		}
+		err := c.conn.WriteControl(PongMessage, []byte(appData), time.Now().Add(controlWriteWait))
+		c.flushControl()
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		if e, ok := err.(net.Error); ok && e.Temporary() {
+			return nil
+		}
+		return err
+	})
+	c.conn.SetPongHandler(func(appData string) error {
+		atomic.StoreInt32(&c.missedPongs, 0)
+		c.KeepHeartbeat()
+		if c.onPong != nil {
+			c.onPong(c, []byte(appData))
+		}
+		return nil
+	})
+}
+
+// ping 主动发送 ping 控制帧, 连续未收到 pong 的次数超过上限或发送失败时返回 false
+func (c *Connection) ping() bool {
+	if atomic.AddInt32(&c.missedPongs, 1) > c.maxMissedPongs {
+		return false
+	}
+	err := c.conn.WriteControl(PingMessage, nil, time.Now().Add(controlWriteWait))
+	c.flushControl()
+	return err == nil
+}
+
+// closeWithCode 发送带状态码的关闭帧后关闭连接, reason 同时作为关闭帧的描述与关闭原因
+func (c *Connection) closeWithCode(code int, reason error) error {
+	return c.closeWithText(code, reason.Error(), reason)
+}
+
+// closeWithText 发送带状态码与描述的关闭帧后关闭连接
+func (c *Connection) closeWithText(code int, text string, reason error) error {
+	_ = c.conn.WriteControl(CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(controlWriteWait))
+	return c.close(reason)
+}
@@ -0,0 +1,71 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnPingOnPong(t *testing.T) {
+	pings := make(chan string, 1)
+	pongs := make(chan string, 1)
+	opts := &Options{
+		OnPing: func(conn *Connection, data []byte) { pings <- string(data) },
+		OnPong: func(conn *Connection, data []byte) { pongs <- string(data) },
+	}
+	client := newTestServer(t, opts, func(conn *Connection) {
+		_ = conn.conn.WriteControl(PingMessage, []byte("server"), time.Now().Add(time.Second))
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	clientPongs := make(chan string, 1)
+	client.SetPongHandler(func(appData string) error {
+		clientPongs <- appData
+		return nil
+	})
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	if err := client.WriteControl(PingMessage, []byte("client"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		ch   chan string
+		want string
+	}{
+		{"OnPing", pings, "client"},
+		{"OnPong", pongs, "server"},
+		{"pong reply", clientPongs, "client"},
+	} {
+		select {
+		case got := <-c.ch:
+			if got != c.want {
+				t.Fatalf("%s: got %q, want %q", c.name, got, c.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: timeout", c.name)
+		}
+	}
+}
+
+func TestMissedPongsClose(t *testing.T) {
+	closed := make(chan struct{})
+	opts := &Options{PingInterval: 1, MaxMissedPongs: 1}
+	// 客户端不读取消息, 因此不会回复 pong
+	client := newTestServer(t, opts, func(conn *Connection) {
+		_, err := conn.Receive()
+		if err == ErrConnClose {
+			close(closed)
+		}
+	})
+	defer client.Close()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after missed pongs")
+	}
+}
@@ -0,0 +1,98 @@
+package gows
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+)
+
+// KeyProvider 提供连接使用的密钥, 可按连接返回不同的密钥, 如握手阶段协商出的会话密钥.
+type KeyProvider interface {
+	Key(conn *Connection) ([]byte, error)
+}
+
+// KeyProviderFunc 函数形式的 KeyProvider
+type KeyProviderFunc func(conn *Connection) ([]byte, error)
+
+// Key 获取密钥
+func (f KeyProviderFunc) Key(conn *Connection) ([]byte, error) {
+	return f(conn)
+}
+
+// StaticKey 所有连接使用同一个密钥
+func StaticKey(key []byte) KeyProvider {
+	return KeyProviderFunc(func(conn *Connection) ([]byte, error) {
+		return key, nil
+	})
+}
+
+// Encryptor 基于 AES-GCM 的消息加密拦截器, 出站消息加密, 入站消息解密. 密钥长度须为 16、24 或 32 字节.
+// 密文格式为 nonce 加 GCM 密文, 文本消息的密文会再做 base64 编码以保证是合法的 UTF-8 文本.
+type Encryptor struct {
+	keys KeyProvider
+}
+
+// NewEncryptor 新建 Encryptor实例.
+func NewEncryptor(keys KeyProvider) *Encryptor {
+	return &Encryptor{keys: keys}
+}
+
+// Use 在连接上注册加解密拦截器
+func (e *Encryptor) Use(conn *Connection) {
+	conn.UseInbound(e.Decrypt)
+	conn.UseOutbound(e.Encrypt)
+}
+
+// Encrypt 加密消息, 可作为出站拦截器使用
+func (e *Encryptor) Encrypt(conn *Connection, msg *Message) (*Message, error) {
+	aead, err := e.aead(conn)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	data := aead.Seal(nonce, nonce, msg.Data, nil)
+	if msg.MessageType == TextMessage {
+		data = []byte(base64.StdEncoding.EncodeToString(data))
+	}
+	return msg.withData(data), nil
+}
+
+// Decrypt 解密消息, 可作为入站拦截器使用. 密文非法或被篡改时返回 ErrDecrypt
+func (e *Encryptor) Decrypt(conn *Connection, msg *Message) (*Message, error) {
+	aead, err := e.aead(conn)
+	if err != nil {
+		return nil, err
+	}
+	data := msg.Data
+	if msg.MessageType == TextMessage {
+		if data, err = base64.StdEncoding.DecodeString(string(data)); err != nil {
+			return nil, ErrDecrypt
+		}
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	if data, err = aead.Open(nil, nonce, ciphertext, nil); err != nil {
+		return nil, ErrDecrypt
+	}
+	return msg.withData(data), nil
+}
+
+// aead 根据连接的密钥创建 AES-GCM 实例
+func (e *Encryptor) aead(conn *Connection) (cipher.AEAD, error) {
+	key, err := e.keys.Key(conn)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,37 @@
+package gows
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncryptor(t *testing.T) {
+	e := NewEncryptor(StaticKey(bytes.Repeat([]byte("k"), 32)))
+	conn := NewConnection()
+	for _, msgType := range []int{TextMessage, BinaryMessage} {
+		plain := &Message{MessageType: msgType, Data: []byte("hello"), Deadline: time.Unix(100, 0), OrderKey: "chat-1"}
+		sealed, err := e.Encrypt(conn, plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(sealed.Data, plain.Data) {
+			t.Fatal("payload not encrypted")
+		}
+		opened, err := e.Decrypt(conn, sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(opened.Data, plain.Data) || opened.MessageType != msgType {
+			t.Fatalf("got %d %q, want %d %q", opened.MessageType, opened.Data, msgType, plain.Data)
+		}
+		if !sealed.Deadline.Equal(plain.Deadline) || sealed.OrderKey != plain.OrderKey {
+			t.Fatalf("got deadline %v order key %q, want metadata kept", sealed.Deadline, sealed.OrderKey)
+		}
+	}
+	sealed, _ := e.Encrypt(conn, &Message{MessageType: BinaryMessage, Data: []byte("hello")})
+	sealed.Data[len(sealed.Data)-1] ^= 0xff
+	if _, err := e.Decrypt(conn, sealed); err != ErrDecrypt {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
@@ -0,0 +1,56 @@
+package gows
+
+import "sync/atomic"
+
+// globalDropped 所有连接被丢弃的消息数, 原子读写
+var globalDropped int64
+
+// DeadLetterHandler 消息被丢弃时的回调, outbound 表示是否为待发送的消息, reason 为丢弃原因
+type DeadLetterHandler func(conn *Connection, msg *Message, outbound bool, reason error)
+
+// Dropped 获取连接被丢弃的消息数
+func (c *Connection) Dropped() int64 {
+	return atomic.LoadInt64(&c.dropped)
+}
+
+// GlobalDropped 获取所有连接被丢弃的消息数
+func GlobalDropped() int64 {
+	return atomic.LoadInt64(&globalDropped)
+}
+
+// drop 记录被丢弃的消息并回调 deadLetter
+func (c *Connection) drop(msg *Message, outbound bool, reason error) {
+	atomic.AddInt64(&c.dropped, 1)
+	atomic.AddInt64(&globalDropped, 1)
+	if c.deadLetter != nil {
+		c.protect(func() {
+			c.deadLetter(c, msg, outbound, reason)
+		})
+	}
+}
+
+// drainOut 写协程退出后处理写队列中剩余的消息, 配置了重试队列时交给重试队列, 否则丢弃
+func (c *Connection) drainOut() {
+	for {
+		var pending pendingMessage
+		select {
+		case pending = <-c.urgentChan:
+		case pending = <-c.retryChan:
+		case pending = <-c.outChan:
+		default:
+			var ok bool
+			if pending, ok = c.topics.pop(); !ok {
+				return
+			}
+		}
+		c.releaseMemory(pending.size)
+		if pending.done != nil {
+			pending.done <- ErrConnClose
+		}
+		if c.retry != nil {
+			c.retry.push(pending, ErrConnClose)
+		} else {
+			c.drop(pending.msg, true, ErrConnClose)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeadLetter(t *testing.T) {
+	var reasons []error
+	conn := NewConnection(
+		WithMemoryBudget(4, MemoryPolicyDrop),
+		WithDeadLetter(func(conn *Connection, msg *Message, outbound bool, reason error) {
+			if !outbound {
+				t.Errorf("got inbound drop of %q", msg.Data)
+			}
+			reasons = append(reasons, reason)
+		}),
+	)
+	before := GlobalDropped()
+	_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("too large")})
+	_ = conn.Close()
+	_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("ok")})
+	if len(reasons) != 2 || !errors.Is(reasons[0], ErrMemoryBudget) || !errors.Is(reasons[1], ErrConnClose) {
+		t.Fatalf("got reasons %v", reasons)
+	}
+	if conn.Dropped() != 2 || GlobalDropped()-before != 2 {
+		t.Fatalf("got dropped %d, global delta %d, want 2", conn.Dropped(), GlobalDropped()-before)
+	}
+}
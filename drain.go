@@ -0,0 +1,208 @@
+package gows
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDrainGracePeriod 默认排空宽限期, 与 Kubernetes 默认的 terminationGracePeriodSeconds 一致
+	DefaultDrainGracePeriod = 30 * time.Second
+
+	// drainPollInterval 排空时检查写队列的间隔
+	drainPollInterval = 10 * time.Millisecond
+)
+
+// DrainerOptions 优雅下线可选参数
+type DrainerOptions struct {
+	// RetryAfter 关闭帧中建议的最长重连等待时间, 每个连接在 0~RetryAfter 之间随机取值以错开重连, 为 0 时不发送重连提示
+	RetryAfter time.Duration
+	// Endpoint 返回关闭帧中建议重连的地址, 可为 nil
+	Endpoint func(conn *Connection) string
+}
+
+// Drainer 优雅下线. 开始排空后就绪检查返回 503, Middleware 拒绝新的升级请求,
+// 已跟踪的连接在写队列发送完毕后以 1001 (going away) 关闭, 宽限期结束时仍未关闭的连接被强制关闭.
+// 配置了 RetryAfter 或 Endpoint 时关闭帧携带重连提示, 参见 CloseHint.
+type Drainer struct {
+	// opts 配置
+	opts DrainerOptions
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// draining 是否正在排空
+	draining bool
+	// conns 已开启的连接
+	conns map[*Connection]struct{}
+	// done 排空完成后关闭
+	done chan struct{}
+}
+
+// NewDrainer 新建 Drainer实例.
+func NewDrainer(opts ...*DrainerOptions) *Drainer {
+	d := &Drainer{conns: make(map[*Connection]struct{}), done: make(chan struct{})}
+	if len(opts) > 0 && opts[0] != nil {
+		d.opts = *opts[0]
+	}
+	return d
+}
+
+// Track 跟踪已开启的连接, 连接关闭后自动取消跟踪. 正在排空时直接以 1001 关闭连接
+func (d *Drainer) Track(conn *Connection) {
+	d.mutex.Lock()
+	if d.draining {
+		d.mutex.Unlock()
+		d.close(conn)
+		return
+	}
+	d.conns[conn] = struct{}{}
+	d.mutex.Unlock()
+	go func() {
+		<-conn.closeChan
+		d.mutex.Lock()
+		delete(d.conns, conn)
+		d.mutex.Unlock()
+	}()
+}
+
+// Draining 是否正在排空
+func (d *Drainer) Draining() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.draining
+}
+
+// Done 排空完成后关闭的通道
+func (d *Drainer) Done() <-chan struct{} {
+	return d.done
+}
+
+// ReadyHandler 就绪检查, 排空开始后回复 503, 可用作 Kubernetes readinessProbe
+func (d *Drainer) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Middleware 排空开始后拒绝新的升级请求, 回复 503 并以 RetryAfter 向上取整的秒数(至少1秒)设置 Retry-After
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			retryAfter := 1
+			if d.opts.RetryAfter > time.Second {
+				retryAfter = int((d.opts.RetryAfter + time.Second - 1) / time.Second)
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Drain 开始排空并等待所有连接关闭. ctx 结束时强制关闭剩余连接并返回 ctx.Err(). 重复调用时等待同一次排空完成
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mutex.Lock()
+	if d.draining {
+		d.mutex.Unlock()
+		select {
+		case <-d.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	d.draining = true
+	conns := make([]*Connection, 0, len(d.conns))
+	for conn := range d.conns {
+		conns = append(conns, conn)
+	}
+	d.mutex.Unlock()
+	defer close(d.done)
+	var wg sync.WaitGroup
+	wg.Add(len(conns))
+	for _, conn := range conns {
+		go func(conn *Connection) {
+			defer wg.Done()
+			d.drainConn(ctx, conn)
+		}(conn)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// NotifyOn 收到 sigs 中的信号时以 grace 为宽限期开始排空, sigs 通常为 syscall.SIGTERM,
+// grace 为 0 时使用 DefaultDrainGracePeriod. 通过 Done 等待排空完成后再退出进程. 返回的函数用于停止监听
+func (d *Drainer) NotifyOn(grace time.Duration, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	if grace <= 0 {
+		grace = DefaultDrainGracePeriod
+	}
+	ch := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		select {
+		case <-ch:
+			ctx, cancel := context.WithTimeout(context.Background(), grace)
+			defer cancel()
+			_ = d.Drain(ctx)
+		case <-quit:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+}
+
+// drainConn 等待连接的写队列发送完毕后以 1001 关闭, ctx 结束时立即关闭
+func (d *Drainer) drainConn(ctx context.Context, conn *Connection) {
+	ticker := conn.clock.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for conn.outDepth() > 0 {
+		select {
+		case <-ticker.C():
+		case <-conn.closeChan:
+			return
+		case <-ctx.Done():
+			d.close(conn)
+			return
+		}
+	}
+	d.close(conn)
+}
+
+// close 以 1001 关闭连接, 按配置附带重连提示
+func (d *Drainer) close(conn *Connection) {
+	if d.opts.RetryAfter <= 0 && d.opts.Endpoint == nil {
+		_ = conn.closeWithCode(websocket.CloseGoingAway, ErrDraining)
+		return
+	}
+	hint := &CloseHint{}
+	if d.opts.RetryAfter > 0 {
+		hint.RetryAfter = time.Duration(rand.Int63n(int64(d.opts.RetryAfter)))
+	}
+	if d.opts.Endpoint != nil {
+		hint.Endpoint = d.opts.Endpoint(conn)
+	}
+	if err := conn.CloseWithHint(websocket.CloseGoingAway, hint, ErrDraining); err != nil {
+		// 提示过长时退回不带提示的关闭帧
+		_ = conn.closeWithCode(websocket.CloseGoingAway, ErrDraining)
+	}
+}
@@ -0,0 +1,58 @@
+package gows
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	d := NewDrainer()
+	client := newTestServer(t, nil, func(conn *Connection) {
+		d.Track(conn)
+		for i := 0; i < 10; i++ {
+			_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("tick")})
+		}
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := client.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 排空前已入队的消息全部送达后才收到关闭帧
+	received := 1
+	for {
+		_, _, err := client.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Fatalf("got %v, want going away close", err)
+			}
+			break
+		}
+		received++
+	}
+	if received != 10 {
+		t.Fatalf("got %d messages before close, want 10", received)
+	}
+	for _, h := range []http.Handler{d.ReadyHandler(), d.Middleware(http.NotFoundHandler())} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("got %d, want 503 while draining", w.Code)
+		}
+	}
+	select {
+	case <-d.Done():
+	default:
+		t.Fatal("Done not closed after Drain")
+	}
+}
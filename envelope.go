@@ -0,0 +1,125 @@
+package gows
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// ErrorEvent 结构化错误回复的事件名
+	ErrorEvent = "error"
+
+	// ErrorCodeInvalidPayload 消息体校验失败
+	ErrorCodeInvalidPayload = "invalid_payload"
+
+	// ErrorCodeUnsupportedVersion 不支持的协议版本
+	ErrorCodeUnsupportedVersion = "unsupported_version"
+
+	// ErrorCodeUnavailable 服务暂不可用
+	ErrorCodeUnavailable = "unavailable"
+
+	// ErrorCodeForbidden 无权限
+	ErrorCodeForbidden = "forbidden"
+
+	// ErrorCodeRateLimited 超出速率限制
+	ErrorCodeRateLimited = "rate_limited"
+)
+
+// Envelope 标准消息信封, 上层协议统一使用该结构作为线上格式.
+type Envelope struct {
+	// ID 消息ID
+	ID string `json:"id"`
+	// Event 事件名
+	Event string `json:"event"`
+	// Timestamp 消息创建时间, Unix 毫秒
+	Timestamp int64 `json:"ts"`
+	// Headers 附加头部
+	Headers map[string]string `json:"headers,omitempty"`
+	// Payload 消息体
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ErrorPayload 结构化错误回复的消息体
+type ErrorPayload struct {
+	// Code 错误码
+	Code string `json:"code"`
+	// Message 错误描述
+	Message string `json:"message"`
+	// Ref 引发错误的消息ID
+	Ref string `json:"ref,omitempty"`
+}
+
+// NewEnvelope 新建 Envelope实例, payload 将被编码为 JSON. 消息ID默认为 UUID, 可通过 SetEnvelopeIDGenerator 替换.
+func NewEnvelope(event string, payload interface{}) (*Envelope, error) {
+	e := &Envelope{
+		ID:        envelopeIDGenerator(),
+		Event:     event,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		e.Payload = data
+	}
+	return e, nil
+}
+
+// DecodeEnvelope 解码信封, 缺少事件名时返回 ErrInvalidEnvelope
+func DecodeEnvelope(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	if e.Event == "" {
+		return nil, fmt.Errorf("%w: missing event", ErrInvalidEnvelope)
+	}
+	return e, nil
+}
+
+// Encode 编码信封
+func (e *Envelope) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Bind 将消息体解码到 v
+func (e *Envelope) Bind(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Message 将信封编码为文本消息
+func (e *Envelope) Message() (*Message, error) {
+	data, err := e.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &Message{MessageType: TextMessage, Data: data}, nil
+}
+
+// WriteEnvelope 写入信封, 与客户端协商了 BinaryEnvelopeSubprotocol 时以二进制格式发送
+func (c *Connection) WriteEnvelope(e *Envelope) error {
+	encode := e.Message
+	if c.conn != nil && c.Subprotocol() == BinaryEnvelopeSubprotocol {
+		encode = e.BinaryMessage
+	}
+	msg, err := encode()
+	if err != nil {
+		return err
+	}
+	return c.Write(msg)
+}
+
+// WriteError 向客户端回复结构化错误, ref 为引发错误的信封, 可为 nil
+func (c *Connection) WriteError(ref *Envelope, code, message string) error {
+	payload := &ErrorPayload{Code: code, Message: message}
+	if ref != nil {
+		payload.Ref = ref.ID
+	}
+	e, err := NewEnvelope(ErrorEvent, payload)
+	if err != nil {
+		return err
+	}
+	return c.WriteEnvelope(e)
+}
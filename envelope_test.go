@@ -0,0 +1,32 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	e, err := NewEnvelope("chat", map[string]string{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Headers = map[string]string{"trace": "1"}
+	data, err := e.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]string
+	if err = got.Bind(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != e.ID || got.Event != "chat" || got.Timestamp != e.Timestamp || got.Headers["trace"] != "1" || payload["text"] != "hi" {
+		t.Fatalf("got %+v, want %+v", got, e)
+	}
+	if _, err = DecodeEnvelope([]byte(`{"id":"1"}`)); !errors.Is(err, ErrInvalidEnvelope) {
+		t.Fatalf("got %v, want ErrInvalidEnvelope", err)
+	}
+}
@@ -0,0 +1,83 @@
+package gows
+
+import (
+	"sync"
+	"time"
+)
+
+// ephemeralSlots 瞬时消息槽, 每个键只保留最新的一条消息
+type ephemeralSlots struct {
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// slots 各键待发送的消息, 入槽时间与截止时间在写入时确定
+	slots map[string]pendingMessage
+	// order 有待发送消息的键, 按写入顺序排列
+	order []string
+	// notify 有待发送消息的通知
+	notify chan struct{}
+}
+
+// newEphemeralSlots 新建 ephemeralSlots
+func newEphemeralSlots() *ephemeralSlots {
+	return &ephemeralSlots{
+		slots:  make(map[string]pendingMessage),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// put 写入消息, 键已有待发送的消息时直接替换, 返回是否替换了旧消息
+func (s *ephemeralSlots) put(key string, pending pendingMessage) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, replaced := s.slots[key]
+	if !replaced {
+		s.order = append(s.order, key)
+	}
+	s.slots[key] = pending
+	s.signal()
+	return replaced
+}
+
+// pop 按写入顺序取出一条消息
+func (s *ephemeralSlots) pop() (pendingMessage, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.order) == 0 {
+		return pendingMessage{}, false
+	}
+	key := s.order[0]
+	s.order = s.order[1:]
+	pending := s.slots[key]
+	delete(s.slots, key)
+	if len(s.order) > 0 {
+		s.signal()
+	}
+	return pending, true
+}
+
+// signal 通知写协程有待发送的消息, 调用方需持有 mutex
+func (s *ephemeralSlots) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// WriteEphemeral 写入瞬时消息, 适用于"正在输入"之类只关心最新状态的信号.
+// 每个 key 只保留一条待发送的消息, 写协程来不及发送时新消息直接覆盖旧消息, 因此慢消费者只会收到最新值.
+// 瞬时消息不占用写队列, 不计入内存预算, 过期、被出站拦截器拒绝、写入失败或连接关闭时直接丢弃,
+// 不进入重试队列与死信回调. 瞬时消息不受顺序键约束, 同一顺序键的消息等待重试时也会立即写入.
+// 消息的有效期从写入槽时开始计算, 在槽中等待超过有效期的消息不会被发送
+func (c *Connection) WriteEphemeral(key string, msg *Message) error {
+	select {
+	case <-c.closeChan:
+		return ErrConnClose
+	default:
+	}
+	pending := pendingMessage{msg: msg, ephemeral: true, deadline: msg.deadline(c.clock.Now())}
+	if c.latency != nil {
+		pending.enqueuedAt = time.Now()
+	}
+	c.ephemeral.put(key, pending)
+	return nil
+}
@@ -0,0 +1,95 @@
+package gows
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteEphemeral(t *testing.T) {
+	conn := NewConnection()
+	for _, data := range []string{"typing:1", "typing:2", "typing:3"} {
+		_ = conn.WriteEphemeral("alice", &Message{MessageType: TextMessage, Data: []byte(data)})
+	}
+	_ = conn.WriteEphemeral("bob", &Message{MessageType: TextMessage, Data: []byte("typing:bob")})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 开启前写入的瞬时消息每个键只保留最新的一条
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"typing:3", "typing:bob"} {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("got %q, want %q", data, want)
+		}
+	}
+	_ = client.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, data, err := client.ReadMessage(); err == nil {
+		t.Fatalf("got unexpected message %q", data)
+	}
+	_ = conn.Close()
+	if err = conn.WriteEphemeral("alice", &Message{MessageType: TextMessage}); err != ErrConnClose {
+		t.Fatalf("got %v, want ErrConnClose", err)
+	}
+}
+
+func TestWriteEphemeralSkipsDeadLetter(t *testing.T) {
+	q := NewRetryQueue()
+	q.push(pendingMessage{msg: &Message{OrderKey: "chat-1"}}, errors.New("broken pipe"))
+	dropped := 0
+	conn := NewConnection(WithRetryQueue(q), WithDeadLetter(func(conn *Connection, msg *Message, outbound bool, reason error) {
+		dropped++
+	}))
+	rejected := errors.New("rejected")
+	conn.UseOutbound(func(conn *Connection, msg *Message) (*Message, error) {
+		return nil, rejected
+	})
+	expired := (&Message{MessageType: TextMessage}).WithTTL(-time.Second)
+	if err := conn.write(pendingMessage{msg: expired, ephemeral: true, deadline: expired.deadline(time.Now())}); err != ErrMessageExpired {
+		t.Fatalf("got %v, want ErrMessageExpired", err)
+	}
+	msg := &Message{MessageType: TextMessage, OrderKey: "chat-1"}
+	if err := conn.write(pendingMessage{msg: msg, ephemeral: true}); err != rejected {
+		t.Fatalf("got %v, want ephemeral message not held behind order key", err)
+	}
+	if dropped != 0 || q.Len() != 1 {
+		t.Fatalf("got %d dead letters and %d retry entries, want 0 and 1", dropped, q.Len())
+	}
+}
+
+func TestWriteEphemeralStamps(t *testing.T) {
+	conn := NewConnection(WithLatencyTracking())
+	_ = conn.WriteEphemeral("alice", (&Message{MessageType: TextMessage, Data: []byte("typing")}).WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	pending, ok := conn.ephemeral.pop()
+	if !ok {
+		t.Fatal("ephemeral message not stored")
+	}
+	if pending.enqueuedAt.IsZero() {
+		t.Fatal("enqueue time not stamped on write")
+	}
+	// 在槽中等待超过有效期的消息不会被发送
+	if err := conn.write(pending); err != ErrMessageExpired {
+		t.Fatalf("got %v, want ErrMessageExpired", err)
+	}
+	if s := conn.latency.QueueWait.Snapshot(); s.Count != 1 || s.Sum > time.Second {
+		t.Fatalf("got %d samples summing %v, want one short queue wait", s.Count, s.Sum)
+	}
+}
@@ -0,0 +1,87 @@
+package gows
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// CreditEvent 流控额度帧的事件名, 消息体为 CreditPayload
+const CreditEvent = "credit"
+
+// CreditPayload 流控额度帧的消息体
+type CreditPayload struct {
+	// Credits 授予的额度, 即对端还可以发送的消息数
+	Credits int `json:"credits"`
+}
+
+// PauseRead 暂停读取底层连接, 让 TCP 流控对客户端形成背压, 而不是在内存中无限缓冲.
+// 正在进行中的一次读取不受影响, 暂停在其后生效. 暂停期间也不会处理 ping/pong 控制帧,
+// 开启心跳检测时应确保暂停时长小于心跳间隔.
+func (c *Connection) PauseRead() {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+	if c.resumeChan == nil {
+		c.resumeChan = make(chan struct{})
+	}
+}
+
+// ResumeRead 恢复读取底层连接
+func (c *Connection) ResumeRead() {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+	if c.resumeChan != nil {
+		close(c.resumeChan)
+		c.resumeChan = nil
+	}
+}
+
+// waitReadResume 暂停读取时阻塞直到恢复, 连接关闭时返回 false
+func (c *Connection) waitReadResume() bool {
+	c.pauseMutex.Lock()
+	resumeChan := c.resumeChan
+	c.pauseMutex.Unlock()
+	if resumeChan == nil {
+		return true
+	}
+	select {
+	case <-resumeChan:
+		return true
+	case <-c.closeChan:
+		return false
+	}
+}
+
+// GrantCredits 向对端授予 n 个额度, 对端最多可再发送 n 条消息
+func (c *Connection) GrantCredits(n int) error {
+	e, err := NewEnvelope(CreditEvent, &CreditPayload{Credits: n})
+	if err != nil {
+		return err
+	}
+	return c.WriteEnvelope(e)
+}
+
+// Credits 流控模式下剩余可发送的消息数
+func (c *Connection) Credits() int {
+	return int(atomic.LoadInt64(&c.credits))
+}
+
+// handleCredit 处理对端授予额度的帧, 是额度帧时返回 true
+func (c *Connection) handleCredit(msg *Message) bool {
+	if !bytes.Contains(msg.Data, []byte(CreditEvent)) {
+		return false
+	}
+	e, err := DecodeMessageEnvelope(msg)
+	if err != nil || e.Event != CreditEvent {
+		return false
+	}
+	payload := &CreditPayload{}
+	if err = e.Bind(payload); err != nil || payload.Credits <= 0 {
+		return true
+	}
+	atomic.AddInt64(&c.credits, int64(payload.Credits))
+	select {
+	case c.creditChan <- struct{}{}:
+	default:
+	}
+	return true
+}
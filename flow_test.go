@@ -0,0 +1,93 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"testing"
+	"time"
+)
+
+func TestPauseResumeRead(t *testing.T) {
+	received := make(chan string, 3)
+	paused := make(chan *Connection, 1)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.PauseRead()
+		paused <- conn
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			received <- string(msg.Data)
+		}
+	})
+	defer client.Close()
+	conn := <-paused
+	for _, data := range []string{"1", "2", "3"} {
+		if err := client.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 暂停前已开始的读取仍会完成, 因此暂停期间最多收到一条消息
+	time.Sleep(100 * time.Millisecond)
+	if n := len(received); n > 1 {
+		t.Fatalf("got %d messages while paused, want at most 1", n)
+	}
+	conn.ResumeRead()
+	for i := 1; i <= 3; i++ {
+		select {
+		case got := <-received:
+			if got != string(rune('0'+i)) {
+				t.Fatalf("got %q, want %d", got, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not delivered after resume", i)
+		}
+	}
+}
+
+func TestFlowControlCredits(t *testing.T) {
+	client := newTestServer(t, WithFlowControl(1), func(conn *Connection) {
+		for _, data := range []string{"1", "2", "3"} {
+			_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte(data)})
+		}
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	msgs := make(chan string, 3)
+	go func() {
+		for {
+			_, data, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- string(data)
+		}
+	}()
+	select {
+	case got := <-msgs:
+		if got != "1" {
+			t.Fatalf("got %q, want 1", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message 1 not delivered with initial credit")
+	}
+	// 额度耗尽, 不应再收到消息
+	select {
+	case got := <-msgs:
+		t.Fatalf("got %q without credits", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := client.WriteMessage(websocket.TextMessage, []byte(`{"event":"credit","payload":{"credits":2}}`)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2", "3"} {
+		select {
+		case got := <-msgs:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %s not delivered after credits granted", want)
+		}
+	}
+}
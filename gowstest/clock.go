@@ -0,0 +1,175 @@
+// Package gowstest 提供测试 gows 的辅助工具.
+package gowstest
+
+import (
+	"github.com/lcr2000/goWs"
+	"sync"
+	"time"
+)
+
+// FakeClock 可手动推进的时钟, 实现了 gows.Clock 接口
+type FakeClock struct {
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// cond 定时器数量变化通知
+	cond *sync.Cond
+	// now 当前时间
+	now time.Time
+	// timers 等待触发的定时器, 触发或停止后移除
+	timers []*fakeTimer
+	// created 已创建的定时器数量
+	created int
+}
+
+// NewFakeClock 新建 FakeClock实例, 初始时间为 now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Now 当前时间
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer 新建定时器
+func (c *FakeClock) NewTimer(d time.Duration) gows.Timer {
+	return c.newTimer(d, 0)
+}
+
+// NewTicker 新建周期定时器
+func (c *FakeClock) NewTicker(d time.Duration) gows.Ticker {
+	return fakeTicker{c.newTimer(d, d)}
+}
+
+// Advance 推进时间, 触发到期的定时器
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			active = append(active, t)
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			for !t.deadline.After(c.now) {
+				t.deadline = t.deadline.Add(t.period)
+			}
+			active = append(active, t)
+		} else {
+			t.active = false
+		}
+	}
+	for i := len(active); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = active
+}
+
+// WaitForTimers 阻塞直到累计创建的定时器数量达到 n, 用于确保被测代码已开始计时再推进时间
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.created < n {
+		c.cond.Wait()
+	}
+}
+
+// Len 返回等待触发的定时器数量
+func (c *FakeClock) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+// remove 移除定时器, 调用方需持有 mutex
+func (c *FakeClock) remove(t *fakeTimer) {
+	for i, timer := range c.timers {
+		if timer == t {
+			copy(c.timers[i:], c.timers[i+1:])
+			c.timers[len(c.timers)-1] = nil
+			c.timers = c.timers[:len(c.timers)-1]
+			return
+		}
+	}
+}
+
+// newTimer 新建定时器, period 大于 0 时为周期定时器
+func (c *FakeClock) newTimer(d, period time.Duration) *fakeTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTimer{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+		period:   period,
+		active:   true,
+	}
+	c.timers = append(c.timers, t)
+	c.created++
+	c.cond.Broadcast()
+	return t
+}
+
+// fakeTimer FakeClock 的定时器
+type fakeTimer struct {
+	// clock 所属时钟
+	clock *FakeClock
+	// c 定时器通道
+	c chan time.Time
+	// deadline 下次触发时间
+	deadline time.Time
+	// period 触发周期, 0 表示一次性定时器
+	period time.Duration
+	// active 是否等待触发
+	active bool
+}
+
+// C 定时器通道
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop 停止定时器, 定时器处于等待触发状态时返回 true
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.active
+	if active {
+		t.active = false
+		t.clock.remove(t)
+	}
+	return active
+}
+
+// Reset 重新设置触发时间, 定时器处于等待触发状态时返回 true
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.active
+	t.deadline = t.clock.now.Add(d)
+	if !active {
+		t.active = true
+		t.clock.timers = append(t.clock.timers, t)
+	}
+	return active
+}
+
+// fakeTicker FakeClock 的周期定时器
+type fakeTicker struct {
+	*fakeTimer
+}
+
+// Stop 停止周期定时器
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
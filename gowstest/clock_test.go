@@ -0,0 +1,70 @@
+package gowstest
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/lcr2000/goWs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeClockHeartbeatTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := gows.NewConnection(gows.WithClock(clock), gows.WithHeartbeat(10),
+			gows.WithOnClose(func(conn *gows.Connection, reason error) { closed <- reason }))
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	clock.WaitForTimers(1)
+	clock.Advance(5 * time.Second)
+	select {
+	case <-closed:
+		t.Fatal("closed before heartbeat interval elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(6 * time.Second)
+	select {
+	case reason := <-closed:
+		if reason != gows.ErrHeartbeatTimeout {
+			t.Fatalf("got %v, want ErrHeartbeatTimeout", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after heartbeat timeout")
+	}
+}
+
+func TestFakeClockRemovesTimers(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	fired := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+	stopped.Stop()
+	clock.Advance(time.Second)
+	<-fired.C()
+	if n := clock.Len(); n != 1 {
+		t.Fatalf("got %d timers, want only the ticker kept", n)
+	}
+	fired.Reset(time.Second)
+	if n := clock.Len(); n != 2 {
+		t.Fatalf("got %d timers, want reset timer added back", n)
+	}
+	clock.Advance(time.Second)
+	<-fired.C()
+	if n := clock.Len(); n != 1 {
+		t.Fatalf("got %d timers, want fired timer removed", n)
+	}
+}
@@ -0,0 +1,114 @@
+package gows
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultHashRingReplicas 默认每个节点的虚拟节点数
+const DefaultHashRingReplicas = 100
+
+// HashRing 一致性哈希环, 将用户ID等键确定性地映射到节点.
+// 节点增减时只有少量键的归属发生变化, 前置代理可用同样的节点列表计算粘性路由
+type HashRing struct {
+	// mutex 保护以下字段
+	mutex sync.RWMutex
+	// replicas 每个节点的虚拟节点数
+	replicas int
+	// hashes 已排序且不重复的虚拟节点哈希值
+	hashes []uint32
+	// owners 虚拟节点哈希值到节点的映射, 多个节点的虚拟节点哈希冲突时归属名称最小的节点
+	owners map[uint32]string
+	// nodes 已加入的节点
+	nodes map[string]struct{}
+}
+
+// NewHashRing 新建一致性哈希环, replicas 为每个节点的虚拟节点数, 不大于 0 时使用默认值
+func NewHashRing(replicas int, nodes ...string) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+	r := &HashRing{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]struct{}),
+	}
+	r.Add(nodes...)
+	return r
+}
+
+// Add 加入节点, 已存在的节点将被忽略
+func (r *HashRing) Add(nodes ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, node := range nodes {
+		r.nodes[node] = struct{}{}
+	}
+	r.rebuild()
+}
+
+// Remove 移除节点
+func (r *HashRing) Remove(node string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.nodes[node]; !ok {
+		return
+	}
+	delete(r.nodes, node)
+	r.rebuild()
+}
+
+// rebuild 根据节点重建哈希环, 结果与节点的加入顺序无关, 调用方需持有 mutex
+func (r *HashRing) rebuild() {
+	r.owners = make(map[uint32]string, len(r.nodes)*r.replicas)
+	r.hashes = r.hashes[:0]
+	for node := range r.nodes {
+		for i := 0; i < r.replicas; i++ {
+			h := hashKey(strconv.Itoa(i) + "#" + node)
+			owner, ok := r.owners[h]
+			if !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			if !ok || node < owner {
+				r.owners[h] = node
+			}
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Nodes 获取已加入的节点
+func (r *HashRing) Nodes() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Get 获取键所属的节点, 哈希环为空时返回空字符串
+func (r *HashRing) Get(key string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// hashKey 计算键的哈希值
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
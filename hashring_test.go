@@ -0,0 +1,64 @@
+package gows
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashRing(t *testing.T) {
+	ring := NewHashRing(0, "node-a", "node-b", "node-c")
+	if got := NewHashRing(0, "node-c", "node-b", "node-a").Get("user-1"); got != ring.Get("user-1") {
+		t.Fatalf("got %q, want same owner regardless of node order", got)
+	}
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := "user-" + strconv.Itoa(i)
+		owners[key] = ring.Get(key)
+	}
+	ring.Remove("node-b")
+	for key, owner := range owners {
+		got := ring.Get(key)
+		if got == "node-b" {
+			t.Fatalf("key %s still routed to removed node", key)
+		}
+		if owner != "node-b" && got != owner {
+			t.Fatalf("key %s moved from %s to %s", key, owner, got)
+		}
+	}
+	if nodes := ring.Nodes(); len(nodes) != 2 {
+		t.Fatalf("got nodes %v, want 2", nodes)
+	}
+	if got := NewHashRing(0).Get("user-1"); got != "" {
+		t.Fatalf("got %q from empty ring", got)
+	}
+}
+
+func TestHashRingCollision(t *testing.T) {
+	// 找出虚拟节点哈希冲突的两个节点
+	seen := make(map[uint32]string)
+	var a, b string
+	for i := 0; a == ""; i++ {
+		node := "n" + strconv.Itoa(i)
+		h := hashKey("0#" + node)
+		if other, ok := seen[h]; ok {
+			a, b = other, node
+		}
+		seen[h] = node
+	}
+	if b < a {
+		a, b = b, a
+	}
+	for _, order := range [][]string{{a, b}, {b, a}} {
+		ring := NewHashRing(1, order...)
+		if len(ring.hashes) != 1 || ring.owners[ring.hashes[0]] != a {
+			t.Fatalf("order %v: got hashes %v owners %v, want one point owned by %s", order, ring.hashes, ring.owners, a)
+		}
+		if got := ring.Get("any"); got != a {
+			t.Fatalf("order %v: got %q, want %q", order, got, a)
+		}
+		ring.Remove(a)
+		if got := ring.Get("any"); got != b {
+			t.Fatalf("order %v: got %q after removing %s, want %q", order, got, a, b)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gows
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// AutoPongOptions 文本心跳自动应答配置.
+// 很多 JS 客户端以文本消息 "ping" 作为心跳, 匹配到这类消息时自动回复并刷新心跳时间.
+type AutoPongOptions struct {
+	// Payloads 需要匹配的入站消息内容, 如 "ping"
+	Payloads []string
+	// Reply 应答内容, 如 "pong", 消息类型与入站消息一致
+	Reply string
+	// Hide 为 true 时匹配到的消息不会被 Receive 读取
+	Hide bool
+}
+
+// match 判断入站消息是否为心跳消息
+func (o *AutoPongOptions) match(data []byte) bool {
+	for _, p := range o.Payloads {
+		if p == string(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHeartbeatInterval 调整心跳检测间隔, 对运行中的连接立即生效, 非正数将被忽略
+func (c *Connection) SetHeartbeatInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	atomic.StoreInt64(&c.heartbeatInterval, int64(d))
+	select {
+	case c.heartbeatChan <- struct{}{}:
+	default:
+	}
+}
+
+// getHeartbeatInterval 获取心跳检测间隔
+func (c *Connection) getHeartbeatInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.heartbeatInterval))
+}
+
+// jitter 在 d 的基础上随机增加 0~d*heartbeatJitter 的时长
+func (c *Connection) jitter(d time.Duration) time.Duration {
+	if c.heartbeatJitter <= 0 || c.heartbeatJitter > 1 {
+		return d
+	}
+	n := int64(float64(d) * c.heartbeatJitter)
+	if n <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(n))
+}
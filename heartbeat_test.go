@@ -0,0 +1,80 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"testing"
+	"time"
+)
+
+func TestAutoPong(t *testing.T) {
+	received := make(chan string, 2)
+	opts := &Options{
+		AutoPong: &AutoPongOptions{Payloads: []string{"ping"}, Reply: "pong", Hide: true},
+	}
+	client := newTestServer(t, opts, func(conn *Connection) {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			received <- string(msg.Data)
+		}
+	})
+	defer client.Close()
+	for _, data := range []string{"ping", "hello"} {
+		if err := client.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "pong" {
+		t.Fatalf("got %q, want pong", data)
+	}
+	select {
+	case got := <-received:
+		if got != "hello" {
+			t.Fatalf("Receive got %q, want hello", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestSetHeartbeatInterval(t *testing.T) {
+	closed := make(chan struct{})
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.SetHeartbeatInterval(100 * time.Millisecond)
+		_, err := conn.Receive()
+		if err == ErrConnClose {
+			close(closed)
+		}
+	})
+	defer client.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after heartbeat interval changed")
+	}
+}
+
+func TestHeartbeatJitter(t *testing.T) {
+	conn := NewConnection(WithHeartbeatJitter(0.5))
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := conn.jitter(10 * time.Second)
+		if d < 10*time.Second || d >= 15*time.Second {
+			t.Fatalf("jittered interval %v out of range", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("intervals not jittered")
+	}
+	if _, err := New(WithHeartbeatJitter(2)); err == nil {
+		t.Fatal("want error for jitter > 1")
+	}
+}
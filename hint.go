@@ -0,0 +1,61 @@
+package gows
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// maxCloseReason 关闭帧描述的最大长度, 控制帧负载不超过 125 字节, 其中 2 字节为状态码
+const maxCloseReason = 123
+
+// CloseHint 关闭帧中的重连提示, 以紧凑 JSON 作为关闭帧的描述发送, 客户端通过 ParseCloseHint 解析,
+// 据此错开重连时间并转向健康的节点, 避免排空或降载时产生重连风暴
+type CloseHint struct {
+	// RetryAfter 建议的重连等待时间, 毫秒精度
+	RetryAfter time.Duration
+	// Endpoint 建议重连的地址, 为空时重连原地址
+	Endpoint string
+}
+
+// closeHintWire CloseHint 的线上格式
+type closeHintWire struct {
+	// RetryAfter 重连等待时间, 毫秒
+	RetryAfter int64 `json:"retry_after"`
+	// Endpoint 重连地址
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// encode 编码为关闭帧描述, 超过 123 字节时返回 ErrInvalidOption
+func (h *CloseHint) encode() (string, error) {
+	data, err := json.Marshal(&closeHintWire{
+		RetryAfter: int64(h.RetryAfter / time.Millisecond),
+		Endpoint:   h.Endpoint,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxCloseReason {
+		return "", fmt.Errorf("%w: close hint is %d bytes, limit %d", ErrInvalidOption, len(data), maxCloseReason)
+	}
+	return string(data), nil
+}
+
+// ParseCloseHint 从关闭帧的描述中解析重连提示, 描述不是重连提示时返回 false
+func ParseCloseHint(reason string) (*CloseHint, bool) {
+	var wire closeHintWire
+	if len(reason) == 0 || reason[0] != '{' || json.Unmarshal([]byte(reason), &wire) != nil {
+		return nil, false
+	}
+	return &CloseHint{RetryAfter: time.Duration(wire.RetryAfter) * time.Millisecond, Endpoint: wire.Endpoint}, true
+}
+
+// CloseWithHint 发送带重连提示的关闭帧后关闭连接, reason 为本端记录的关闭原因.
+// 提示编码后超过关闭帧描述的长度上限时返回 ErrInvalidOption, 连接不会被关闭
+func (c *Connection) CloseWithHint(code int, hint *CloseHint, reason error) error {
+	text, err := hint.encode()
+	if err != nil {
+		return err
+	}
+	return c.closeWithText(code, text, reason)
+}
@@ -0,0 +1,43 @@
+package gows
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseHint(t *testing.T) {
+	d := NewDrainer(&DrainerOptions{
+		RetryAfter: 5 * time.Second,
+		Endpoint:   func(conn *Connection) string { return "wss://b.example.com/ws" },
+	})
+	client := newTestServer(t, nil, func(conn *Connection) {
+		d.Track(conn)
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	go func() { _ = d.Drain(context.Background()) }()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := client.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Fatalf("got %v, want going away close", err)
+	}
+	hint, ok := ParseCloseHint(closeErr.Text)
+	if !ok {
+		t.Fatalf("got close text %q, want hint", closeErr.Text)
+	}
+	if hint.Endpoint != "wss://b.example.com/ws" || hint.RetryAfter < 0 || hint.RetryAfter >= 5*time.Second {
+		t.Fatalf("got %+v", hint)
+	}
+	if _, ok = ParseCloseHint(ErrDraining.Error()); ok {
+		t.Fatal("plain close text parsed as hint")
+	}
+	long := &CloseHint{Endpoint: "wss://" + strings.Repeat("a", 120)}
+	if err = NewConnection().CloseWithHint(websocket.CloseGoingAway, long, ErrDraining); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,144 @@
+package gows
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// snowflakeNodeBits 雪花ID中节点ID的位数
+	snowflakeNodeBits = 10
+	// snowflakeSeqBits 雪花ID中序列号的位数
+	snowflakeSeqBits = 12
+	// MaxSnowflakeNode 雪花ID节点ID的最大值
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+)
+
+// snowflakeEpoch 雪花ID时间戳的起始时间, 2020-01-01 UTC
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// crockford ULID 使用的 Crockford base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// envelopeIDGenerator NewEnvelope 使用的消息ID生成器
+var envelopeIDGenerator IDGenerator = uuid.NewString
+
+// IDGenerator ID生成器, 需并发安全
+type IDGenerator func() string
+
+// SetEnvelopeIDGenerator 设置 NewEnvelope 使用的消息ID生成器, 为 nil 时恢复默认的 UUID.
+// 应在创建信封前调用, 不可与 NewEnvelope 并发调用
+func SetEnvelopeIDGenerator(gen IDGenerator) {
+	if gen == nil {
+		gen = uuid.NewString
+	}
+	envelopeIDGenerator = gen
+}
+
+// Snowflake 雪花ID生成器, 64位ID由毫秒时间戳(41位)、节点ID(10位)与序列号(12位)组成,
+// 同一节点生成的ID按生成时间递增. 多节点部署时各节点需使用不同的节点ID
+type Snowflake struct {
+	// mutex 保护 last 与 seq
+	mutex sync.Mutex
+	// node 节点ID
+	node int64
+	// last 最近一次生成ID的毫秒时间戳
+	last int64
+	// seq 当前毫秒内的序列号
+	seq int64
+}
+
+// NewSnowflake 新建 Snowflake实例, node 取值 0~MaxSnowflakeNode, 否则返回 ErrInvalidOption.
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("%w: snowflake node %d out of range", ErrInvalidOption, node)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// Next 生成下一个ID. 同一毫秒内序列号耗尽时等待下一毫秒, 系统时钟回拨时沿用最近一次的时间戳
+func (s *Snowflake) Next() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	if now < s.last {
+		now = s.last
+	}
+	if now == s.last {
+		s.seq = (s.seq + 1) & (1<<snowflakeSeqBits - 1)
+		if s.seq == 0 {
+			for now <= s.last {
+				time.Sleep(time.Millisecond)
+				now = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = now
+	return now<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq
+}
+
+// NextString 生成下一个ID的十进制字符串, 可作为 IDGenerator 使用
+func (s *Snowflake) NextString() string {
+	return strconv.FormatInt(s.Next(), 10)
+}
+
+// ULID 生成器, 26位字符串由毫秒时间戳(48位)与随机数(80位)组成, 按字典序即按生成时间排序.
+// 同一毫秒内生成的ID随机部分递增, 保证单调
+type ULID struct {
+	// mutex 保护 last 与 entropy
+	mutex sync.Mutex
+	// last 最近一次生成ID的毫秒时间戳
+	last uint64
+	// entropy 最近一次生成ID的随机部分
+	entropy [10]byte
+}
+
+// NewULID 新建 ULID实例.
+func NewULID() *ULID {
+	return &ULID{}
+}
+
+// Next 生成下一个ID, 可作为 IDGenerator 使用
+func (u *ULID) Next() string {
+	u.mutex.Lock()
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if now <= u.last {
+		// 同一毫秒内或时钟回拨时, 随机部分加一
+		for i := len(u.entropy) - 1; i >= 0; i-- {
+			u.entropy[i]++
+			if u.entropy[i] != 0 {
+				break
+			}
+		}
+		now = u.last
+	} else {
+		_, _ = rand.Read(u.entropy[:])
+		u.last = now
+	}
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[:2], uint16(now>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(now))
+	copy(raw[6:], u.entropy[:])
+	u.mutex.Unlock()
+	return encodeULID(raw)
+}
+
+// encodeULID 以 Crockford base32 编码 128 位 ULID
+func encodeULID(raw [16]byte) string {
+	hi, lo := binary.BigEndian.Uint64(raw[:8]), binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	// 128 位按 5 位一组从低位开始编码, 最高位一组只有 3 位
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
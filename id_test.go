@@ -0,0 +1,64 @@
+package gows
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestSnowflake(t *testing.T) {
+	if _, err := NewSnowflake(MaxSnowflakeNode + 1); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+	s, err := NewSnowflake(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last int64
+	for i := 0; i < 10000; i++ {
+		id := s.Next()
+		if id <= last {
+			t.Fatalf("id %d not greater than %d", id, last)
+		}
+		if node := id >> snowflakeSeqBits & MaxSnowflakeNode; node != 7 {
+			t.Fatalf("got node %d, want 7", node)
+		}
+		last = id
+	}
+}
+
+func TestULID(t *testing.T) {
+	u := NewULID()
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = u.Next()
+		if len(ids[i]) != 26 {
+			t.Fatalf("got %q, want 26 characters", ids[i])
+		}
+		if i > 0 && ids[i] <= ids[i-1] {
+			t.Fatalf("id %q not greater than %q", ids[i], ids[i-1])
+		}
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("ids not sorted")
+	}
+	if got := encodeULID([16]byte{15: 1}); got != "00000000000000000000000001" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	conn := NewConnection(WithIDGenerator(func() string { return "fixed" }))
+	if conn.GetConnID() != "fixed" {
+		t.Fatalf("got %q, want fixed", conn.GetConnID())
+	}
+	SetEnvelopeIDGenerator(NewULID().Next)
+	defer SetEnvelopeIDGenerator(nil)
+	e, err := NewEnvelope("chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.ID) != 26 {
+		t.Fatalf("got %q, want ULID", e.ID)
+	}
+}
@@ -0,0 +1,148 @@
+package gows
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader 信封中携带幂等键的头部
+	IdempotencyKeyHeader = "idempotency-key"
+
+	// DefaultIdempotencyWindow 默认幂等结果保留时间
+	DefaultIdempotencyWindow = 5 * time.Minute
+)
+
+// idempotencyEntry 一次命令的执行结果
+type idempotencyEntry struct {
+	// done 执行完成后关闭
+	done chan struct{}
+	// reply 执行结果
+	reply *Envelope
+	// succeeded 执行是否成功, 失败或 panic 时等待者重新执行命令
+	succeeded bool
+	// expire 过期时间
+	expire time.Time
+}
+
+// IdempotencyCache 幂等命令缓存, 在保留时间内记住带幂等键的命令的回复,
+// 客户端重试同一命令时直接返回缓存的回复而不重复执行, 适用于至少一次投递的客户端.
+// 幂等键按用户隔离, 未绑定用户ID的连接共用同一个命名空间.
+type IdempotencyCache struct {
+	// window 保留时间
+	window time.Duration
+	// mutex 保护 entries 与 nextPrune
+	mutex sync.Mutex
+	// entries 幂等键及其执行结果
+	entries map[string]*idempotencyEntry
+	// nextPrune 下次清理过期结果的时间
+	nextPrune time.Time
+}
+
+// NewIdempotencyCache 新建 IdempotencyCache实例, window 为 0 时使用 DefaultIdempotencyWindow.
+func NewIdempotencyCache(window time.Duration) *IdempotencyCache {
+	if window <= 0 {
+		window = DefaultIdempotencyWindow
+	}
+	return &IdempotencyCache{window: window, entries: make(map[string]*idempotencyEntry)}
+}
+
+// Handle 执行命令并写入回复. 信封未携带幂等键时直接执行; 保留时间内已执行过时写入缓存的回复,
+// 同一命令正在执行时等待其完成. fn 返回 error 或 panic 时不缓存结果, 等待中的重复命令与客户端重试都将重新执行. 回复为 nil 时不写入
+func (c *IdempotencyCache) Handle(conn *Connection, e *Envelope, fn func() (*Envelope, error)) error {
+	key := e.Headers[IdempotencyKeyHeader]
+	if key == "" {
+		return c.reply(conn, fn)
+	}
+	key = conn.GetUserID() + "\x00" + key
+	for {
+		now := conn.clock.Now()
+		c.mutex.Lock()
+		c.prune(now)
+		entry, ok := c.entries[key]
+		if ok && !entry.expire.IsZero() && now.After(entry.expire) {
+			ok = false
+		}
+		if !ok {
+			entry = &idempotencyEntry{done: make(chan struct{})}
+			c.entries[key] = entry
+		}
+		c.mutex.Unlock()
+		if !ok {
+			return c.execute(conn, key, entry, fn)
+		}
+		select {
+		case <-entry.done:
+		case <-conn.closeChan:
+			return ErrConnClose
+		}
+		if !entry.succeeded {
+			// 首次执行失败, 条目已删除, 重新竞争执行权
+			continue
+		}
+		if entry.reply == nil {
+			return nil
+		}
+		return conn.WriteEnvelope(entry.reply)
+	}
+}
+
+// execute 执行命令并记录结果, 完成后唤醒等待者
+func (c *IdempotencyCache) execute(conn *Connection, key string, entry *idempotencyEntry, fn func() (*Envelope, error)) error {
+	finished := false
+	defer func() {
+		// fn panic 时删除条目并唤醒等待者, panic 继续向上传递
+		if !finished {
+			c.mutex.Lock()
+			delete(c.entries, key)
+			c.mutex.Unlock()
+			close(entry.done)
+		}
+	}()
+	reply, err := fn()
+	finished = true
+	c.mutex.Lock()
+	if err != nil {
+		delete(c.entries, key)
+	} else {
+		entry.reply = reply
+		entry.succeeded = true
+		entry.expire = conn.clock.Now().Add(c.window)
+	}
+	c.mutex.Unlock()
+	close(entry.done)
+	if err != nil || reply == nil {
+		return err
+	}
+	return conn.WriteEnvelope(reply)
+}
+
+// Len 返回缓存的结果数
+func (c *IdempotencyCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// reply 执行命令并写入回复
+func (c *IdempotencyCache) reply(conn *Connection, fn func() (*Envelope, error)) error {
+	reply, err := fn()
+	if err != nil || reply == nil {
+		return err
+	}
+	return conn.WriteEnvelope(reply)
+}
+
+// prune 清理过期结果, 调用方需持有 mutex
+func (c *IdempotencyCache) prune(now time.Time) {
+	if now.Before(c.nextPrune) {
+		return
+	}
+	for k, entry := range c.entries {
+		// 正在执行的命令 expire 为零值, 不清理
+		if !entry.expire.IsZero() && now.After(entry.expire) {
+			delete(c.entries, k)
+		}
+	}
+	c.nextPrune = now.Add(c.window)
+}
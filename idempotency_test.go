@@ -0,0 +1,126 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	executed := 0
+	client := newTestServer(t, nil, func(conn *Connection) {
+		cmd := &Envelope{Event: "charge", Headers: map[string]string{IdempotencyKeyHeader: "k1"}}
+		fn := func() (*Envelope, error) {
+			executed++
+			return NewEnvelope("charged", executed)
+		}
+		for i := 0; i < 3; i++ {
+			if err := cache.Handle(conn, cmd, fn); err != nil {
+				t.Error(err)
+			}
+		}
+		failed := &Envelope{Event: "charge", Headers: map[string]string{IdempotencyKeyHeader: "k2"}}
+		if err := cache.Handle(conn, failed, func() (*Envelope, error) { return nil, errors.New("boom") }); err == nil {
+			t.Error("got nil, want error")
+		}
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < 3; i++ {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := DecodeEnvelope(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(e.Payload) != "1" {
+			t.Fatalf("reply %d: got payload %s, want 1", i, e.Payload)
+		}
+	}
+	if executed != 1 {
+		t.Fatalf("got %d executions, want 1", executed)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("got %d cached results, want 1 (failed command must not be cached)", cache.Len())
+	}
+}
+
+func TestIdempotencyCachePanic(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	conn := NewConnection()
+	cmd := &Envelope{Event: "charge", Headers: map[string]string{IdempotencyKeyHeader: "k1"}}
+	conn.protect(func() {
+		_ = cache.Handle(conn, cmd, func() (*Envelope, error) {
+			panic("boom")
+		})
+	})
+	if cache.Len() != 0 {
+		t.Fatalf("got %d entries, want entry removed after panic", cache.Len())
+	}
+	// 重试不会永久等待 panic 的执行结果
+	done := make(chan error, 1)
+	go func() {
+		done <- cache.Handle(NewConnection(), cmd, func() (*Envelope, error) {
+			return nil, nil
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retry blocked after panic")
+	}
+}
+
+func TestIdempotencyCacheRetryAfterFailure(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	conn := NewConnection()
+	cmd := &Envelope{Event: "charge", Headers: map[string]string{IdempotencyKeyHeader: "k1"}}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := make(chan error, 1)
+	go func() {
+		first <- cache.Handle(conn, cmd, func() (*Envelope, error) {
+			close(started)
+			<-release
+			return nil, errors.New("boom")
+		})
+	}()
+	<-started
+	executed := make(chan struct{}, 1)
+	second := make(chan error, 1)
+	go func() {
+		second <- cache.Handle(conn, cmd, func() (*Envelope, error) {
+			executed <- struct{}{}
+			return nil, nil
+		})
+	}()
+	// 等待重复命令进入等待状态
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-first; err == nil {
+		t.Fatal("got nil, want error from failed command")
+	}
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("duplicate blocked after failure")
+	}
+	select {
+	case <-executed:
+	default:
+		t.Fatal("duplicate of a failed command was not executed")
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("got %d cached results, want the retried result cached", cache.Len())
+	}
+}
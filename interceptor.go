@@ -0,0 +1,45 @@
+package gows
+
+// Interceptor 消息拦截器, 可对消息进行转换、补充或过滤.
+// 返回 error 或 nil 消息时该消息将被丢弃, 后续拦截器不再执行.
+type Interceptor func(conn *Connection, msg *Message) (*Message, error)
+
+// UseInbound 追加入站拦截器, 按添加顺序在消息进入读队列前执行.
+// 在 Open 之后调用时, 此前已到达的消息不会经过该拦截器, 需要覆盖所有消息时使用 WithInbound
+func (c *Connection) UseInbound(fns ...Interceptor) {
+	c.interceptorMutex.Lock()
+	defer c.interceptorMutex.Unlock()
+	c.inboundInterceptors = append(c.inboundInterceptors, fns...)
+}
+
+// UseOutbound 追加出站拦截器, 按添加顺序在消息写入底层连接前执行
+func (c *Connection) UseOutbound(fns ...Interceptor) {
+	c.interceptorMutex.Lock()
+	defer c.interceptorMutex.Unlock()
+	c.outboundInterceptors = append(c.outboundInterceptors, fns...)
+}
+
+// inbound 获取入站拦截器链
+func (c *Connection) inbound() []Interceptor {
+	c.interceptorMutex.RLock()
+	defer c.interceptorMutex.RUnlock()
+	return c.inboundInterceptors
+}
+
+// outbound 获取出站拦截器链
+func (c *Connection) outbound() []Interceptor {
+	c.interceptorMutex.RLock()
+	defer c.interceptorMutex.RUnlock()
+	return c.outboundInterceptors
+}
+
+// intercept 依次执行拦截器链
+func (c *Connection) intercept(chain []Interceptor, msg *Message) (*Message, error) {
+	var err error
+	for _, fn := range chain {
+		if msg, err = fn(c, msg); err != nil || msg == nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,54 @@
+package gows
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gorilla/websocket"
+	"testing"
+	"time"
+)
+
+func TestInterceptors(t *testing.T) {
+	// 拦截器在 Open 之前安装, 避免客户端的第一条消息先于拦截器到达
+	opts := &Options{
+		Inbound: []Interceptor{func(conn *Connection, msg *Message) (*Message, error) {
+			if string(msg.Data) == "drop" {
+				return nil, errors.New("rejected")
+			}
+			return msg, nil
+		}},
+		Outbound: []Interceptor{
+			func(conn *Connection, msg *Message) (*Message, error) {
+				msg.Data = bytes.ToUpper(msg.Data)
+				return msg, nil
+			},
+			func(conn *Connection, msg *Message) (*Message, error) {
+				msg.Data = append([]byte("echo:"), msg.Data...)
+				return msg, nil
+			},
+		},
+	}
+	client := newTestServer(t, opts, func(conn *Connection) {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			_ = conn.Write(msg)
+		}
+	})
+	defer client.Close()
+	for _, data := range []string{"drop", "hello"} {
+		if err := client.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "echo:HELLO" {
+		t.Fatalf("got %q, want echo:HELLO", data)
+	}
+}
@@ -0,0 +1,278 @@
+package gows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIntrospectCacheTTL 默认令牌自省结果缓存时间
+	DefaultIntrospectCacheTTL = time.Minute
+
+	// DefaultIntrospectRevalidateInterval 默认长连接令牌重新校验间隔
+	DefaultIntrospectRevalidateInterval = 5 * time.Minute
+
+	// DefaultIntrospectTimeout 默认自省请求超时时间
+	DefaultIntrospectTimeout = 5 * time.Second
+
+	// DefaultIntrospectCacheSize 默认自省结果缓存的最大条目数
+	DefaultIntrospectCacheSize = 10000
+)
+
+// TokenInfo RFC 7662 令牌自省响应
+type TokenInfo struct {
+	// Active 令牌是否有效
+	Active bool `json:"active"`
+	// Scope 授权范围, 空格分隔
+	Scope string `json:"scope,omitempty"`
+	// ClientID 客户端ID
+	ClientID string `json:"client_id,omitempty"`
+	// Username 用户名
+	Username string `json:"username,omitempty"`
+	// Subject 令牌主体, 通常为用户ID
+	Subject string `json:"sub,omitempty"`
+	// ExpiresAt 过期时间, Unix 秒, 0 表示未返回
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// expired 判断令牌在 now 时是否已过期
+func (t *TokenInfo) expired(now time.Time) bool {
+	return t.ExpiresAt > 0 && now.Unix() >= t.ExpiresAt
+}
+
+// IntrospectorOptions 令牌自省可选参数
+type IntrospectorOptions struct {
+	// Endpoint 授权服务的自省地址
+	Endpoint string
+	// ClientID 访问自省地址的客户端ID, 为空时不使用 Basic 认证
+	ClientID string
+	// ClientSecret 访问自省地址的客户端密钥
+	ClientSecret string
+	// CacheTTL 自省结果缓存时间, 不会超过令牌的过期时间, 默认1min
+	CacheTTL time.Duration
+	// RevalidateInterval 长连接令牌重新校验间隔, 默认5min
+	RevalidateInterval time.Duration
+	// Timeout 自省请求超时时间, 默认5s
+	Timeout time.Duration
+	// CacheSize 自省结果缓存的最大条目数, 清理过期条目后仍已满时不再缓存新结果, 默认10000
+	CacheSize int
+	// Clock Introspect 判断缓存与令牌是否过期使用的时钟, 默认为系统时钟. Watch 使用连接的时钟
+	Clock Clock
+}
+
+// introspectEntry 自省结果缓存项
+type introspectEntry struct {
+	// info 自省结果
+	info *TokenInfo
+	// expires 缓存过期时间
+	expires time.Time
+}
+
+// Introspector 通过 RFC 7662 令牌自省校验 Bearer 令牌, 结果会被缓存.
+// Middleware 在升级前校验令牌, Watch 定期重新校验长连接, 令牌过期或被吊销时关闭连接.
+type Introspector struct {
+	// opts 配置
+	opts IntrospectorOptions
+	// client http 客户端
+	client *http.Client
+	// mutex 保护 cache 与 nextPrune
+	mutex sync.Mutex
+	// cache 令牌到自省结果的缓存
+	cache map[string]*introspectEntry
+	// nextPrune 下次清理过期结果的时间
+	nextPrune time.Time
+}
+
+// NewIntrospector 新建 Introspector实例
+func NewIntrospector(opts *IntrospectorOptions) *Introspector {
+	o := *opts
+	if o.CacheTTL <= 0 {
+		o.CacheTTL = DefaultIntrospectCacheTTL
+	}
+	if o.RevalidateInterval <= 0 {
+		o.RevalidateInterval = DefaultIntrospectRevalidateInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultIntrospectTimeout
+	}
+	if o.CacheSize <= 0 {
+		o.CacheSize = DefaultIntrospectCacheSize
+	}
+	if o.Clock == nil {
+		o.Clock = realClock{}
+	}
+	return &Introspector{
+		opts:   o,
+		client: &http.Client{Timeout: o.Timeout},
+		cache:  make(map[string]*introspectEntry),
+	}
+}
+
+// Introspect 校验令牌, 令牌无效或已过期时返回 ErrTokenInactive
+func (i *Introspector) Introspect(ctx context.Context, token string) (*TokenInfo, error) {
+	return i.introspect(ctx, token, i.opts.Clock)
+}
+
+// introspect 按 clock 校验令牌
+func (i *Introspector) introspect(ctx context.Context, token string, clock Clock) (*TokenInfo, error) {
+	now := clock.Now()
+	i.mutex.Lock()
+	entry, ok := i.cache[token]
+	if ok && now.After(entry.expires) {
+		delete(i.cache, token)
+		ok = false
+	}
+	i.mutex.Unlock()
+	if !ok {
+		info, err := i.request(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		entry = &introspectEntry{info: info, expires: now.Add(i.opts.CacheTTL)}
+		if info.ExpiresAt > 0 && time.Unix(info.ExpiresAt, 0).Before(entry.expires) {
+			entry.expires = time.Unix(info.ExpiresAt, 0)
+		}
+		i.mutex.Lock()
+		i.prune(now)
+		if len(i.cache) < i.opts.CacheSize {
+			i.cache[token] = entry
+		}
+		i.mutex.Unlock()
+	}
+	if !entry.info.Active || entry.info.expired(now) {
+		return nil, ErrTokenInactive
+	}
+	return entry.info, nil
+}
+
+// prune 每隔 CacheTTL 清理一次过期结果, 调用方需持有 mutex
+func (i *Introspector) prune(now time.Time) {
+	if now.Before(i.nextPrune) && len(i.cache) < i.opts.CacheSize {
+		return
+	}
+	for token, entry := range i.cache {
+		if now.After(entry.expires) {
+			delete(i.cache, token)
+		}
+	}
+	i.nextPrune = now.Add(i.opts.CacheTTL)
+}
+
+// request 请求授权服务的自省地址
+func (i *Introspector) request(ctx context.Context, token string) (*TokenInfo, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.opts.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.opts.ClientID != "" {
+		req.SetBasicAuth(i.opts.ClientID, i.opts.ClientSecret)
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gows: introspection returned status %d", resp.StatusCode)
+	}
+	info := &TokenInfo{}
+	if err = json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Authenticate 从请求的 Authorization: Bearer 头或 access_token 查询参数中取出令牌并校验
+func (i *Introspector) Authenticate(r *http.Request) (*TokenInfo, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, ErrTokenInactive
+	}
+	return i.Introspect(r.Context(), token)
+}
+
+// Middleware 在升级前校验令牌, 失败时回复 401, 成功时可通过 TokenInfoFromContext 获取自省结果
+func (i *Introspector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := i.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenInfoKey{}, info)))
+	})
+}
+
+// Watch 按 RevalidateInterval 定期重新校验连接的令牌, 令牌过期或被吊销时以 ErrTokenInactive 关闭连接.
+// 授权服务暂不可用时保持连接, 在下一个周期重试, 但已知的令牌过期时间仍在本地强制执行
+func (i *Introspector) Watch(conn *Connection, token string) {
+	go func() {
+		var expiresAt int64
+		i.mutex.Lock()
+		if entry, ok := i.cache[token]; ok {
+			expiresAt = entry.info.ExpiresAt
+		}
+		i.mutex.Unlock()
+		timer := conn.clock.NewTimer(i.revalidateAfter(conn, expiresAt))
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C():
+				info, err := i.introspect(context.Background(), token, conn.clock)
+				if err == nil {
+					expiresAt = info.ExpiresAt
+				}
+				if errors.Is(err, ErrTokenInactive) || expiresAt > 0 && conn.clock.Now().Unix() >= expiresAt {
+					_ = conn.close(ErrTokenInactive)
+					return
+				}
+				timer.Reset(i.revalidateAfter(conn, expiresAt))
+			case <-conn.closeChan:
+				return
+			}
+		}
+	}()
+}
+
+// revalidateAfter 计算距下次校验的时间, 不晚于令牌的过期时间
+func (i *Introspector) revalidateAfter(conn *Connection, expiresAt int64) time.Duration {
+	d := i.opts.RevalidateInterval
+	if expiresAt > 0 {
+		if left := time.Unix(expiresAt, 0).Sub(conn.clock.Now()); left < d {
+			d = left
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// tokenInfoKey 自省结果在 context 中的键
+type tokenInfoKey struct{}
+
+// TokenInfoFromContext 获取 Middleware 写入的自省结果
+func TokenInfoFromContext(ctx context.Context) (*TokenInfo, bool) {
+	info, ok := ctx.Value(tokenInfoKey{}).(*TokenInfo)
+	return info, ok
+}
+
+// BearerToken 从请求的 Authorization: Bearer 头或 access_token 查询参数中取出令牌.
+// 浏览器的 WebSocket API 无法设置请求头, 因此也支持查询参数
+func BearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.URL.Query().Get("access_token")
+}
@@ -0,0 +1,149 @@
+package gows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntrospectorMiddleware(t *testing.T) {
+	var requests int32
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = r.ParseForm()
+		_ = json.NewEncoder(w).Encode(&TokenInfo{Active: r.PostForm.Get("token") == "good", Subject: "u1"})
+	}))
+	defer auth.Close()
+	i := NewIntrospector(&IntrospectorOptions{Endpoint: auth.URL})
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := TokenInfoFromContext(r.Context())
+		_, _ = w.Write([]byte(info.Subject))
+	}))
+	for _, c := range []struct {
+		target string
+		header string
+		code   int
+	}{
+		{"/", "Bearer good", http.StatusOK},
+		{"/?access_token=good", "", http.StatusOK},
+		{"/", "Bearer bad", http.StatusUnauthorized},
+		{"/", "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.code {
+			t.Fatalf("%s %q: got status %d, want %d", c.target, c.header, rec.Code, c.code)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("got %d introspection requests, want 2 with caching", n)
+	}
+}
+
+func TestIntrospectorWatch(t *testing.T) {
+	var revoked int32
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&TokenInfo{Active: atomic.LoadInt32(&revoked) == 0})
+	}))
+	defer auth.Close()
+	i := NewIntrospector(&IntrospectorOptions{
+		Endpoint:           auth.URL,
+		CacheTTL:           time.Millisecond,
+		RevalidateInterval: 10 * time.Millisecond,
+	})
+	conn := NewConnection()
+	i.Watch(conn, "token")
+	time.Sleep(30 * time.Millisecond)
+	if conn.CloseReason() != nil {
+		t.Fatal("connection closed while token active")
+	}
+	atomic.StoreInt32(&revoked, 1)
+	select {
+	case <-conn.closeChan:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after token revoked")
+	}
+	if !errors.Is(conn.CloseReason(), ErrTokenInactive) {
+		t.Fatalf("got close reason %v, want ErrTokenInactive", conn.CloseReason())
+	}
+}
+
+func TestIntrospectorWatchEnforcesExpiry(t *testing.T) {
+	var down int32
+	exp := time.Now().Add(time.Second).Unix() + 1
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&TokenInfo{Active: true, ExpiresAt: exp})
+	}))
+	defer auth.Close()
+	i := NewIntrospector(&IntrospectorOptions{
+		Endpoint:           auth.URL,
+		CacheTTL:           time.Millisecond,
+		RevalidateInterval: time.Hour,
+	})
+	if _, err := i.Introspect(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&down, 1)
+	conn := NewConnection()
+	i.Watch(conn, "token")
+	select {
+	case <-conn.closeChan:
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection not closed after token expired while endpoint down")
+	}
+	if !errors.Is(conn.CloseReason(), ErrTokenInactive) {
+		t.Fatalf("got close reason %v, want ErrTokenInactive", conn.CloseReason())
+	}
+}
+
+func TestIntrospectorCacheBounded(t *testing.T) {
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&TokenInfo{Active: true})
+	}))
+	defer auth.Close()
+	i := NewIntrospector(&IntrospectorOptions{Endpoint: auth.URL, CacheTTL: 10 * time.Millisecond, CacheSize: 2})
+	for _, token := range []string{"a", "b", "c"} {
+		if _, err := i.Introspect(context.Background(), token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(i.cache); n != 2 {
+		t.Fatalf("got %d cached entries, want 2", n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := i.Introspect(context.Background(), "d"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(i.cache); n != 1 {
+		t.Fatalf("got %d cached entries, want expired entries pruned", n)
+	}
+}
+
+func TestIntrospectorClock(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&TokenInfo{Active: true, ExpiresAt: exp})
+	}))
+	defer auth.Close()
+	if _, err := NewIntrospector(&IntrospectorOptions{Endpoint: auth.URL}).Introspect(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	// 令牌是否过期按配置的时钟判断
+	i := NewIntrospector(&IntrospectorOptions{Endpoint: auth.URL, Clock: skewClock{skew: 2 * time.Hour}})
+	if _, err := i.Introspect(context.Background(), "token"); !errors.Is(err, ErrTokenInactive) {
+		t.Fatalf("got %v, want ErrTokenInactive on the configured clock", err)
+	}
+}
@@ -0,0 +1,157 @@
+package gows
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// histogramBase 直方图第一个桶的上界
+	histogramBase = 50 * time.Microsecond
+
+	// histogramBuckets 直方图桶数, 上界依次翻倍, 最后一个桶不设上界
+	histogramBuckets = 22
+)
+
+// globalLatency 所有开启延迟统计的连接的汇总
+var globalLatency = &LatencyStats{QueueWait: &Histogram{}, Write: &Histogram{}}
+
+// Histogram 并发安全的耗时直方图, 桶上界从 50µs 开始依次翻倍.
+type Histogram struct {
+	// base 第一个桶的上界, 为 0 时使用 histogramBase
+	base time.Duration
+	// count 样本数
+	count uint64
+	// sum 样本耗时总和, 纳秒
+	sum uint64
+	// buckets 各桶样本数
+	buckets [histogramBuckets]uint64
+}
+
+// Bucket 直方图桶
+type Bucket struct {
+	// UpperBound 桶上界, 最后一个桶为 0 表示不设上界
+	UpperBound time.Duration
+	// Count 落在该桶内的样本数
+	Count uint64
+}
+
+// HistogramSnapshot 直方图快照
+type HistogramSnapshot struct {
+	// Count 样本数
+	Count uint64
+	// Sum 样本耗时总和
+	Sum time.Duration
+	// Buckets 各桶样本数
+	Buckets []Bucket
+}
+
+// Observe 记录一次耗时
+func (h *Histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i, bound := 0, h.firstBound()
+	for i < histogramBuckets-1 && d > bound {
+		i++
+		bound *= 2
+	}
+	atomic.AddUint64(&h.buckets[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddUint64(&h.sum, uint64(d))
+}
+
+// Snapshot 获取直方图快照
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadUint64(&h.sum)),
+		Buckets: make([]Bucket, histogramBuckets),
+	}
+	bound := h.firstBound()
+	for i := range s.Buckets {
+		s.Buckets[i].Count = atomic.LoadUint64(&h.buckets[i])
+		if i < histogramBuckets-1 {
+			s.Buckets[i].UpperBound = bound
+			bound *= 2
+		}
+	}
+	return s
+}
+
+// firstBound 第一个桶的上界
+func (h *Histogram) firstBound() time.Duration {
+	if h.base > 0 {
+		return h.base
+	}
+	return histogramBase
+}
+
+// Mean 平均耗时
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile 估算分位数耗时, 返回分位数所在桶的上界, q 取值 0~1
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	var total uint64
+	for _, b := range s.Buckets {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(total))
+	var seen uint64
+	for i, b := range s.Buckets {
+		seen += b.Count
+		if seen > rank || i == len(s.Buckets)-1 {
+			if b.UpperBound == 0 {
+				return s.Buckets[i-1].UpperBound * 2
+			}
+			return b.UpperBound
+		}
+	}
+	return 0
+}
+
+// LatencyStats 写路径延迟统计
+type LatencyStats struct {
+	// QueueWait 消息在写队列中的等待时间
+	QueueWait *Histogram
+	// Write 消息出队后经过拦截器并写入底层连接的耗时
+	Write *Histogram
+}
+
+// newLatencyStats 新建延迟统计, enabled 为 false 时返回 nil
+func newLatencyStats(enabled bool) *LatencyStats {
+	if !enabled {
+		return nil
+	}
+	return &LatencyStats{QueueWait: &Histogram{}, Write: &Histogram{}}
+}
+
+// observeQueueWait 记录写队列等待时间, 同时计入全局统计
+func (s *LatencyStats) observeQueueWait(d time.Duration) {
+	s.QueueWait.Observe(d)
+	globalLatency.QueueWait.Observe(d)
+}
+
+// observeWrite 记录写入耗时, 同时计入全局统计
+func (s *LatencyStats) observeWrite(d time.Duration) {
+	s.Write.Observe(d)
+	globalLatency.Write.Observe(d)
+}
+
+// Latency 获取连接的延迟统计, 未开启 TrackLatency 时为 nil
+func (c *Connection) Latency() *LatencyStats {
+	return c.latency
+}
+
+// GlobalLatency 获取所有开启延迟统计的连接的汇总
+func GlobalLatency() *LatencyStats {
+	return globalLatency
+}
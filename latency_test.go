@@ -0,0 +1,46 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{}
+	for i := 0; i < 90; i++ {
+		h.Observe(10 * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(time.Millisecond)
+	}
+	s := h.Snapshot()
+	if s.Count != 100 {
+		t.Fatalf("got count %d, want 100", s.Count)
+	}
+	if got := s.Quantile(0.5); got != histogramBase {
+		t.Fatalf("got p50 %v, want %v", got, histogramBase)
+	}
+	if got := s.Quantile(0.99); got < time.Millisecond || got > 2*time.Millisecond {
+		t.Fatalf("got p99 %v, want ~1ms", got)
+	}
+}
+
+func TestLatencyTracking(t *testing.T) {
+	done := make(chan *Connection, 1)
+	client := newTestServer(t, &Options{TrackLatency: true}, func(conn *Connection) {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("hello")})
+		done <- conn
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	if _, _, err := client.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	conn := <-done
+	if conn.Latency().QueueWait.Snapshot().Count != 1 {
+		t.Fatal("queue wait not observed")
+	}
+	if GlobalLatency().QueueWait.Snapshot().Count == 0 {
+		t.Fatal("global queue wait not observed")
+	}
+}
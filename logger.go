@@ -0,0 +1,51 @@
+package gows
+
+import "strings"
+
+// Logger 日志接口, 标准库 *log.Logger 即实现了该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// nopLogger 不输出任何内容的日志
+type nopLogger struct{}
+
+// Printf 丢弃日志
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+// LeveledLogger 带日志级别的日志接口, zap 的 *zap.SugaredLogger 与 logrus 的 *logrus.Logger、*logrus.Entry 均实现了该接口
+type LeveledLogger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// leveledLogger 按日志内容选择级别输出到 LeveledLogger: panic 与写入失败以 Error 级别输出,
+// 丢弃消息以 Info 级别输出, 读取失败通常由客户端断开引起, 以 Debug 级别输出
+type leveledLogger struct {
+	l LeveledLogger
+}
+
+// Printf 按日志内容选择级别输出日志
+func (w leveledLogger) Printf(format string, v ...interface{}) {
+	switch {
+	case strings.Contains(format, "panic"):
+		w.l.Errorf(format, v...)
+	case strings.Contains(format, "read error"):
+		w.l.Debugf(format, v...)
+	case strings.Contains(format, "dropped"):
+		w.l.Infof(format, v...)
+	default:
+		w.l.Errorf(format, v...)
+	}
+}
+
+// ZapLogger 将 zap 日志适配为 Logger, 传入 logger.Sugar()
+func ZapLogger(sugar LeveledLogger) Logger {
+	return leveledLogger{l: sugar}
+}
+
+// LogrusLogger 将 logrus 日志适配为 Logger, 支持 *logrus.Logger 与 *logrus.Entry
+func LogrusLogger(logger LeveledLogger) Logger {
+	return leveledLogger{l: logger}
+}
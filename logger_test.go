@@ -0,0 +1,46 @@
+package gows
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fakeLeveledLogger 模拟 zap/logrus 的日志接口, 记录每条日志的级别
+type fakeLeveledLogger struct {
+	logs []string
+}
+
+func (f *fakeLeveledLogger) Debugf(format string, args ...interface{}) {
+	f.logs = append(f.logs, "debug "+fmt.Sprintf(format, args...))
+}
+
+func (f *fakeLeveledLogger) Infof(format string, args ...interface{}) {
+	f.logs = append(f.logs, "info "+fmt.Sprintf(format, args...))
+}
+
+func (f *fakeLeveledLogger) Errorf(format string, args ...interface{}) {
+	f.logs = append(f.logs, "error "+fmt.Sprintf(format, args...))
+}
+
+func TestLeveledLoggerAdapters(t *testing.T) {
+	for _, adapt := range []func(l *fakeLeveledLogger) Logger{
+		func(l *fakeLeveledLogger) Logger { return ZapLogger(l) },
+		func(l *fakeLeveledLogger) Logger { return LogrusLogger(l) },
+	} {
+		l := &fakeLeveledLogger{}
+		logger := adapt(l)
+		logger.Printf("gows: connection %s panic: %v", "1", "boom")
+		logger.Printf("gows: connection %s read error: %v", "1", "EOF")
+		logger.Printf("gows: connection %s inbound message dropped: %v", "1", ErrMemoryBudget)
+		logger.Printf("gows: connection %s write error: %v", "1", "broken pipe")
+		want := []string{
+			"error gows: connection 1 panic: boom",
+			"debug gows: connection 1 read error: EOF",
+			"info gows: connection 1 inbound message dropped: " + ErrMemoryBudget.Error(),
+			"error gows: connection 1 write error: broken pipe",
+		}
+		if fmt.Sprint(l.logs) != fmt.Sprint(want) {
+			t.Fatalf("got %q, want %q", l.logs, want)
+		}
+	}
+}
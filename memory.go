@@ -0,0 +1,111 @@
+package gows
+
+import "sync/atomic"
+
+// MemoryPolicy 连接超出内存预算时的处理策略
+type MemoryPolicy int
+
+const (
+	// MemoryPolicyClose 超出预算时关闭连接, 关闭原因为 ErrMemoryBudget
+	MemoryPolicyClose MemoryPolicy = iota
+	// MemoryPolicyDrop 超出预算时丢弃新消息, Write 返回 ErrMemoryBudget, 入站消息记录日志后丢弃
+	MemoryPolicyDrop
+)
+
+// MemoryAccountant 多个连接共享的全局内存预算. 用量达到预算后拒绝新的连接升级,
+// 并丢弃所有连接新入队的消息, 直到用量回落, 避免流量突增时进程因内存耗尽被杀
+type MemoryAccountant struct {
+	// used 已使用的内存, 字节, 原子读写
+	used int64
+	// shed 因超出预算被丢弃的消息数, 原子读写
+	shed int64
+	// budget 内存预算, 字节
+	budget int64
+}
+
+// NewMemoryAccountant 新建全局内存预算, budget 为字节数
+func NewMemoryAccountant(budget int) *MemoryAccountant {
+	return &MemoryAccountant{budget: int64(budget)}
+}
+
+// Usage 获取所有连接读写队列中消息占用的内存, 字节
+func (a *MemoryAccountant) Usage() int64 {
+	return atomic.LoadInt64(&a.used)
+}
+
+// Shed 获取因超出预算被丢弃的消息数
+func (a *MemoryAccountant) Shed() int64 {
+	return atomic.LoadInt64(&a.shed)
+}
+
+// Exceeded 判断是否已达到预算
+func (a *MemoryAccountant) Exceeded() bool {
+	return a.budget > 0 && atomic.LoadInt64(&a.used) >= a.budget
+}
+
+// reserve 预留内存, 超出预算时返回 false
+func (a *MemoryAccountant) reserve(size int) bool {
+	if a.budget <= 0 || atomic.AddInt64(&a.used, int64(size)) <= a.budget {
+		return true
+	}
+	atomic.AddInt64(&a.used, -int64(size))
+	atomic.AddInt64(&a.shed, 1)
+	return false
+}
+
+// release 释放内存
+func (a *MemoryAccountant) release(size int) {
+	if a.budget > 0 {
+		atomic.AddInt64(&a.used, -int64(size))
+	}
+}
+
+// MemoryUsage 获取连接读写队列中消息占用的内存, 字节. 重试队列中的消息不计入
+func (c *Connection) MemoryUsage() int64 {
+	return atomic.LoadInt64(&c.memory)
+}
+
+// reserveMemory 为入队的消息预留内存, 超出连接预算时按策略处理, 超出全局预算时丢弃消息, 均返回 ErrMemoryBudget
+func (c *Connection) reserveMemory(size int) error {
+	used := atomic.AddInt64(&c.memory, int64(size))
+	if c.memoryBudget > 0 && used > c.memoryBudget {
+		atomic.AddInt64(&c.memory, -int64(size))
+		if c.memoryPolicy == MemoryPolicyClose {
+			_ = c.close(ErrMemoryBudget)
+		}
+		return ErrMemoryBudget
+	}
+	if c.memoryAccountant != nil && !c.memoryAccountant.reserve(size) {
+		atomic.AddInt64(&c.memory, -int64(size))
+		return ErrMemoryBudget
+	}
+	return nil
+}
+
+// forceMemory 为入队的消息记录内存, 不检查预算
+func (c *Connection) forceMemory(size int) {
+	atomic.AddInt64(&c.memory, int64(size))
+	if c.memoryAccountant != nil && c.memoryAccountant.budget > 0 {
+		atomic.AddInt64(&c.memoryAccountant.used, int64(size))
+	}
+}
+
+// drainIn 连接关闭后清空读队列, 释放未被读取的消息预留的内存
+func (c *Connection) drainIn() {
+	for {
+		select {
+		case msg := <-c.inChan:
+			c.releaseMemory(len(msg.Data))
+		default:
+			return
+		}
+	}
+}
+
+// releaseMemory 消息出队后释放预留的内存
+func (c *Connection) releaseMemory(size int) {
+	atomic.AddInt64(&c.memory, -int64(size))
+	if c.memoryAccountant != nil {
+		c.memoryAccountant.release(size)
+	}
+}
@@ -0,0 +1,121 @@
+package gows
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudgetDrop(t *testing.T) {
+	conn := NewConnection(WithMemoryBudget(10, MemoryPolicyDrop))
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 8)}
+	if err := conn.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Write(msg); !errors.Is(err, ErrMemoryBudget) {
+		t.Fatalf("got %v, want ErrMemoryBudget", err)
+	}
+	if got := conn.MemoryUsage(); got != 8 {
+		t.Fatalf("got usage %d, want 8", got)
+	}
+	if conn.CloseReason() != nil {
+		t.Fatal("connection closed under drop policy")
+	}
+}
+
+func TestMemoryBudgetClose(t *testing.T) {
+	conn := NewConnection(WithMemoryBudget(10, MemoryPolicyClose))
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 8)}
+	_ = conn.Write(msg)
+	if err := conn.Write(msg); !errors.Is(err, ErrMemoryBudget) {
+		t.Fatalf("got %v, want ErrMemoryBudget", err)
+	}
+	if !errors.Is(conn.CloseReason(), ErrMemoryBudget) {
+		t.Fatalf("got close reason %v, want ErrMemoryBudget", conn.CloseReason())
+	}
+}
+
+func TestMemoryAccountant(t *testing.T) {
+	accountant := NewMemoryAccountant(10)
+	a := NewConnection(WithMemoryAccountant(accountant))
+	b := NewConnection(WithMemoryAccountant(accountant))
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 6)}
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Write(msg); !errors.Is(err, ErrMemoryBudget) {
+		t.Fatalf("got %v, want ErrMemoryBudget", err)
+	}
+	if b.CloseReason() != nil {
+		t.Fatal("connection closed by global budget")
+	}
+	if accountant.Usage() != 6 || accountant.Shed() != 1 {
+		t.Fatalf("got usage %d shed %d, want 6 and 1", accountant.Usage(), accountant.Shed())
+	}
+	_ = a.Write(&Message{MessageType: BinaryMessage, Data: make([]byte, 4)})
+	if !accountant.Exceeded() {
+		t.Fatal("accountant not exceeded at budget")
+	}
+	rec := httptest.NewRecorder()
+	if err := NewConnection(WithMemoryAccountant(accountant)).Open(rec, httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, ErrMemoryBudget) {
+		t.Fatalf("got %v, want ErrMemoryBudget", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want 503", rec.Code)
+	}
+}
+
+func TestMemoryReleasedOnClose(t *testing.T) {
+	accountant := NewMemoryAccountant(1 << 20)
+	closed := make(chan *Connection, 1)
+	client := newTestServer(t, WithMemoryAccountant(accountant), func(conn *Connection) {
+		// 不读取消息, 关闭时读队列中的消息应释放预留的内存
+		for len(conn.inChan) < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		_ = conn.Close()
+		closed <- conn
+	})
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		if err := client.WriteMessage(BinaryMessage, make([]byte, 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn := <-closed
+	deadline := time.Now().Add(time.Second)
+	for conn.MemoryUsage() != 0 || accountant.Usage() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got usage %d, accountant %d after close, want 0", conn.MemoryUsage(), accountant.Usage())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryBudgetUrgent(t *testing.T) {
+	conn := NewConnection(WithMemoryBudget(10, MemoryPolicyDrop))
+	msg := &Message{MessageType: BinaryMessage, Data: make([]byte, 8)}
+	_ = conn.Write(msg)
+	if err := conn.WriteUrgent(msg); err != nil {
+		t.Fatalf("got %v, want urgent message exempt from budget", err)
+	}
+	if err := conn.Write(msg); !errors.Is(err, ErrMemoryBudget) {
+		t.Fatalf("got %v, want ErrMemoryBudget", err)
+	}
+}
+
+func TestMemoryReleasedOnDeliverAfterClose(t *testing.T) {
+	accountant := NewMemoryAccountant(1 << 20)
+	for i := 0; i < 100; i++ {
+		conn := NewConnection(WithMemoryAccountant(accountant))
+		_ = conn.Close()
+		if conn.deliver(&Message{MessageType: BinaryMessage, Data: make([]byte, 100)}) {
+			t.Fatal("got true, want deliver to stop after close")
+		}
+		if conn.MemoryUsage() != 0 || accountant.Usage() != 0 {
+			t.Fatalf("got usage %d, accountant %d, want reservation released", conn.MemoryUsage(), accountant.Usage())
+		}
+	}
+}
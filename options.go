@@ -0,0 +1,441 @@
+package gows
+
+import (
+	"compress/flate"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// Options 可选参数
+type Options struct {
+	// InChanSize 读队列大小, 默认1024
+	InChanSize int
+	// OutChanSize 写队列大小, 默认1024
+	OutChanSize int
+	// HeartbeatInterval 心跳检测间隔, 当心跳间隔大于这个时间连接将断开, 默认300s
+	HeartbeatInterval int
+	// OnPing 收到 ping 控制帧时的回调, data 为 ping 携带的数据. 回调返回后会自动回复 pong
+	OnPing func(conn *Connection, data []byte)
+	// OnPong 收到 pong 控制帧时的回调, data 为 pong 携带的数据
+	OnPong func(conn *Connection, data []byte)
+	// AutoPong 文本心跳自动应答配置, 为 nil 时不开启
+	AutoPong *AutoPongOptions
+	// PingInterval 服务端主动 ping 间隔, 秒, 为 0 时不主动 ping
+	PingInterval int
+	// MaxMissedPongs 连续未收到 pong 的次数超过该值时断开连接, 默认3
+	MaxMissedPongs int
+	// Logger 日志, 默认不输出
+	Logger Logger
+	// Subprotocols 服务端支持的子协议, 按优先级排列
+	Subprotocols []string
+	// OnOpen 连接开启后的回调
+	OnOpen func(conn *Connection)
+	// OnClose 连接关闭后的回调, reason 为关闭原因, 应用主动关闭时为 nil
+	OnClose func(conn *Connection, reason error)
+	// OnPanic 读写协程或回调发生 panic 时的回调, 回调后连接将被关闭
+	OnPanic func(conn *Connection, recovered interface{}, stack []byte)
+	// TrackLatency 是否统计写队列等待时间与写入耗时
+	TrackLatency bool
+	// Clock 心跳检测与主动 ping 使用的时钟, 默认为系统时钟
+	Clock Clock
+	// HeartbeatJitter 心跳检测与主动 ping 间隔的随机抖动比例, 取值 0~1.
+	// 如 0.1 表示每次间隔随机增加 0~10%, 避免大量连接的定时器同时触发
+	HeartbeatJitter float64
+	// RetryQueue 写入失败消息的重试队列, 为 nil 时写入失败的消息直接丢弃
+	RetryQueue *RetryQueue
+	// FlowControl 是否开启基于额度的流控, 开启后每发送一条消息消耗一个额度, 额度耗尽时暂停发送直到对端授予新的额度
+	FlowControl bool
+	// InitialCredits 流控模式下连接开启时的初始额度
+	InitialCredits int
+	// EnableCompression 是否与客户端协商 permessage-deflate 压缩.
+	// gorilla/websocket 仅支持 no_context_takeover 模式, 因此不会为每个连接保留压缩上下文, 内存占用与连接数无关
+	EnableCompression bool
+	// CompressionLevel 压缩级别, 取值 -2~9, 参见 compress/flate. 0 表示使用默认级别1
+	CompressionLevel int
+	// CompressionThreshold 开启压缩时, 小于该字节数的消息不压缩, 0 表示全部压缩
+	CompressionThreshold int
+	// ShouldCompress 开启压缩时, 逐条决定消息是否压缩, 如已压缩的图片等二进制消息可跳过以节省 CPU.
+	// 在 CompressionThreshold 之后判断, 为 nil 时压缩所有达到阈值的消息
+	ShouldCompress func(msg *Message) bool
+	// ReadBufferSize 读缓冲区大小, 字节, 默认4096
+	ReadBufferSize int
+	// WriteBufferSize 写缓冲区大小, 字节, 默认4096
+	WriteBufferSize int
+	// WriteBufferPool 写缓冲区池, 设置后写缓冲区仅在写入消息时占用, 适合大量空闲连接的场景
+	WriteBufferPool BufferPool
+	// ZeroCopy 是否开启零拷贝接收. 开启后 Receive 返回的 Message.Data 引用内部缓冲区,
+	// 仅在下一次 Receive 前有效, 且同一时间只能有一个协程调用 Receive. 适合高频的二进制消息
+	ZeroCopy bool
+	// MemoryBudget 连接读写队列中消息占用内存的上限, 字节, 0 表示不限制.
+	// 用于防止异常客户端不读取或大量发送消息导致积压过多
+	MemoryBudget int
+	// MemoryPolicy 超出内存预算时的处理策略, 默认关闭连接
+	MemoryPolicy MemoryPolicy
+	// MemoryAccountant 多个连接共享的全局内存预算, 达到预算后拒绝新的连接升级并丢弃新入队的消息
+	MemoryAccountant *MemoryAccountant
+	// CertUser 将客户端证书映射为用户ID, 连接开启时通过 SetUserID 绑定. 仅在客户端出示了证书时调用
+	CertUser func(cert *x509.Certificate) string
+	// Batch 批量帧配置, 为 nil 时不开启
+	Batch *BatchOptions
+	// Authenticate 校验升级后客户端发送的第一帧认证消息, 校验通过后 Open 才返回并回调 OnOpen.
+	// 用于浏览器等无法在握手时设置 Authorization 头的客户端, 为 nil 时不等待认证帧
+	Authenticate Authenticator
+	// AuthTimeout 等待认证帧的超时时间, 默认10s
+	AuthTimeout time.Duration
+	// DeadLetter 消息被丢弃时的回调, 如写入已关闭的连接、超出内存预算、拦截器返回错误、写入失败且未配置重试队列等.
+	// 可用于记录或转存丢失的消息
+	DeadLetter DeadLetterHandler
+	// HighWatermark 写队列高水位, 写队列消息数达到该值时回调 OnHighWatermark, 0 表示不开启
+	HighWatermark int
+	// LowWatermark 写队列低水位, 越过高水位后消息数回落到该值时回调 OnLowWatermark, 默认为高水位的一半
+	LowWatermark int
+	// OnHighWatermark 写队列消息数越过高水位时的回调, 可用于暂停为该连接生产开销较大的数据
+	OnHighWatermark func(conn *Connection, depth int)
+	// OnLowWatermark 写队列消息数回落到低水位时的回调, 可用于恢复生产
+	OnLowWatermark func(conn *Connection, depth int)
+	// BufferedWrites 是否开启缓冲写入. 开启后写入的消息先进入大小为 WriteBufferSize 的缓冲区,
+	// 调用 Flush、达到 FlushInterval 或缓冲区写满时才发送, 适合突发写入时合并系统调用. 控制帧总是立即发送
+	BufferedWrites bool
+	// FlushInterval 缓冲写入模式下自动 Flush 的间隔, 0 表示仅在调用 Flush 或缓冲区写满时发送
+	FlushInterval time.Duration
+	// SocketHook 连接升级后对底层连接进行设置, 如通过 TuneTCP 调整 TCP_NODELAY 与 keepalive
+	SocketHook SocketHook
+	// IDGenerator 连接ID生成器, 默认为 UUID. 可使用 Snowflake 或 ULID 生成按创建时间排序的ID
+	IDGenerator IDGenerator
+	// Inbound 入站拦截器, 在连接开启前安装, 保证连接收到的第一条消息即经过拦截器
+	Inbound []Interceptor
+	// Outbound 出站拦截器, 在连接开启前安装
+	Outbound []Interceptor
+}
+
+// BufferPool 写缓冲区池, *sync.Pool 即实现了该接口. 参见 gorilla/websocket 的 BufferPool
+type BufferPool interface {
+	Get() interface{}
+	Put(interface{})
+}
+
+// Option 连接配置项. *Options 与 WithXxx 系列函数均实现了该接口.
+// 传入 *Options 时会整体覆盖此前的配置, 因此应放在 WithXxx 之前.
+type Option interface {
+	apply(o *Options)
+}
+
+// apply 使用 o 整体覆盖 dst
+func (o *Options) apply(dst *Options) {
+	if o != nil {
+		*dst = *o
+	}
+}
+
+// optionFunc 函数式配置项
+type optionFunc func(o *Options)
+
+// apply 执行配置函数
+func (f optionFunc) apply(o *Options) {
+	f(o)
+}
+
+// buildOptions 依次应用配置项
+func buildOptions(opts []Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(o)
+		}
+	}
+	return o
+}
+
+// validate 校验配置值, 0 表示使用默认值, 负数为非法值
+func (o *Options) validate() error {
+	for _, f := range []struct {
+		name  string
+		value int
+	}{
+		{"InChanSize", o.InChanSize},
+		{"OutChanSize", o.OutChanSize},
+		{"HeartbeatInterval", o.HeartbeatInterval},
+		{"PingInterval", o.PingInterval},
+		{"MaxMissedPongs", o.MaxMissedPongs},
+		{"InitialCredits", o.InitialCredits},
+		{"ReadBufferSize", o.ReadBufferSize},
+		{"WriteBufferSize", o.WriteBufferSize},
+		{"CompressionThreshold", o.CompressionThreshold},
+		{"MemoryBudget", o.MemoryBudget},
+		{"HighWatermark", o.HighWatermark},
+		{"LowWatermark", o.LowWatermark},
+	} {
+		if f.value < 0 {
+			return fmt.Errorf("%w: %s %d", ErrInvalidOption, f.name, f.value)
+		}
+	}
+	if o.CompressionLevel < flate.HuffmanOnly || o.CompressionLevel > flate.BestCompression {
+		return fmt.Errorf("%w: CompressionLevel %d", ErrInvalidOption, o.CompressionLevel)
+	}
+	if o.LowWatermark > 0 && o.LowWatermark >= o.HighWatermark {
+		return fmt.Errorf("%w: LowWatermark %d", ErrInvalidOption, o.LowWatermark)
+	}
+	if o.HeartbeatJitter < 0 || o.HeartbeatJitter > 1 {
+		return fmt.Errorf("%w: HeartbeatJitter %v", ErrInvalidOption, o.HeartbeatJitter)
+	}
+	return nil
+}
+
+// WithInChanSize 设置读队列大小
+func WithInChanSize(size int) Option {
+	return optionFunc(func(o *Options) {
+		o.InChanSize = size
+	})
+}
+
+// WithOutChanSize 设置写队列大小
+func WithOutChanSize(size int) Option {
+	return optionFunc(func(o *Options) {
+		o.OutChanSize = size
+	})
+}
+
+// WithHeartbeat 设置心跳检测间隔, 秒
+func WithHeartbeat(interval int) Option {
+	return optionFunc(func(o *Options) {
+		o.HeartbeatInterval = interval
+	})
+}
+
+// WithOnPing 设置收到 ping 控制帧时的回调
+func WithOnPing(fn func(conn *Connection, data []byte)) Option {
+	return optionFunc(func(o *Options) {
+		o.OnPing = fn
+	})
+}
+
+// WithOnPong 设置收到 pong 控制帧时的回调
+func WithOnPong(fn func(conn *Connection, data []byte)) Option {
+	return optionFunc(func(o *Options) {
+		o.OnPong = fn
+	})
+}
+
+// WithAutoPong 设置文本心跳自动应答
+func WithAutoPong(autoPong *AutoPongOptions) Option {
+	return optionFunc(func(o *Options) {
+		o.AutoPong = autoPong
+	})
+}
+
+// WithPing 设置服务端主动 ping 间隔(秒)及允许连续未收到 pong 的次数
+func WithPing(interval, maxMissedPongs int) Option {
+	return optionFunc(func(o *Options) {
+		o.PingInterval = interval
+		o.MaxMissedPongs = maxMissedPongs
+	})
+}
+
+// WithLogger 设置日志
+func WithLogger(logger Logger) Option {
+	return optionFunc(func(o *Options) {
+		o.Logger = logger
+	})
+}
+
+// WithSubprotocols 设置服务端支持的子协议
+func WithSubprotocols(protocols ...string) Option {
+	return optionFunc(func(o *Options) {
+		o.Subprotocols = protocols
+	})
+}
+
+// WithOnOpen 设置连接开启后的回调
+func WithOnOpen(fn func(conn *Connection)) Option {
+	return optionFunc(func(o *Options) {
+		o.OnOpen = fn
+	})
+}
+
+// WithOnClose 设置连接关闭后的回调
+func WithOnClose(fn func(conn *Connection, reason error)) Option {
+	return optionFunc(func(o *Options) {
+		o.OnClose = fn
+	})
+}
+
+// WithOnPanic 设置发生 panic 时的回调
+func WithOnPanic(fn func(conn *Connection, recovered interface{}, stack []byte)) Option {
+	return optionFunc(func(o *Options) {
+		o.OnPanic = fn
+	})
+}
+
+// WithLatencyTracking 开启写队列等待时间与写入耗时统计
+func WithLatencyTracking() Option {
+	return optionFunc(func(o *Options) {
+		o.TrackLatency = true
+	})
+}
+
+// WithClock 设置时钟, 用于在测试中控制心跳与定时逻辑
+func WithClock(clock Clock) Option {
+	return optionFunc(func(o *Options) {
+		o.Clock = clock
+	})
+}
+
+// WithHeartbeatJitter 设置心跳检测与主动 ping 间隔的随机抖动比例, 取值 0~1
+func WithHeartbeatJitter(jitter float64) Option {
+	return optionFunc(func(o *Options) {
+		o.HeartbeatJitter = jitter
+	})
+}
+
+// WithRetryQueue 设置写入失败消息的重试队列
+func WithRetryQueue(q *RetryQueue) Option {
+	return optionFunc(func(o *Options) {
+		o.RetryQueue = q
+	})
+}
+
+// WithFlowControl 开启基于额度的流控, initialCredits 为初始额度
+func WithFlowControl(initialCredits int) Option {
+	return optionFunc(func(o *Options) {
+		o.FlowControl = true
+		o.InitialCredits = initialCredits
+	})
+}
+
+// WithCompression 开启 permessage-deflate 压缩, level 为压缩级别, 取值 -2~9, 0 表示使用默认级别
+func WithCompression(level int) Option {
+	return optionFunc(func(o *Options) {
+		o.EnableCompression = true
+		o.CompressionLevel = level
+	})
+}
+
+// WithCompressionThreshold 设置压缩阈值, 小于 threshold 字节的消息不压缩
+func WithCompressionThreshold(threshold int) Option {
+	return optionFunc(func(o *Options) {
+		o.CompressionThreshold = threshold
+	})
+}
+
+// WithShouldCompress 设置逐条决定消息是否压缩的回调
+func WithShouldCompress(fn func(msg *Message) bool) Option {
+	return optionFunc(func(o *Options) {
+		o.ShouldCompress = fn
+	})
+}
+
+// WithBufferSizes 设置读写缓冲区大小, 字节
+func WithBufferSizes(readBufferSize, writeBufferSize int) Option {
+	return optionFunc(func(o *Options) {
+		o.ReadBufferSize = readBufferSize
+		o.WriteBufferSize = writeBufferSize
+	})
+}
+
+// WithWriteBufferPool 设置写缓冲区池, 同一类连接应共用一个池
+func WithWriteBufferPool(pool BufferPool) Option {
+	return optionFunc(func(o *Options) {
+		o.WriteBufferPool = pool
+	})
+}
+
+// WithZeroCopy 开启零拷贝接收
+func WithZeroCopy() Option {
+	return optionFunc(func(o *Options) {
+		o.ZeroCopy = true
+	})
+}
+
+// WithMemoryBudget 设置连接的内存预算与超出预算时的处理策略
+func WithMemoryBudget(budget int, policy MemoryPolicy) Option {
+	return optionFunc(func(o *Options) {
+		o.MemoryBudget = budget
+		o.MemoryPolicy = policy
+	})
+}
+
+// WithMemoryAccountant 设置全局内存预算, 同一个 MemoryAccountant 应传给所有需要共同计量的连接
+func WithMemoryAccountant(accountant *MemoryAccountant) Option {
+	return optionFunc(func(o *Options) {
+		o.MemoryAccountant = accountant
+	})
+}
+
+// WithCertUser 设置客户端证书到用户ID的映射, 如 func(cert *x509.Certificate) string { return cert.Subject.CommonName }
+func WithCertUser(fn func(cert *x509.Certificate) string) Option {
+	return optionFunc(func(o *Options) {
+		o.CertUser = fn
+	})
+}
+
+// WithBatching 开启批量帧, opts 为 nil 时使用默认配置
+func WithBatching(opts *BatchOptions) Option {
+	return optionFunc(func(o *Options) {
+		o.Batch = opts
+		if o.Batch == nil {
+			o.Batch = &BatchOptions{}
+		}
+	})
+}
+
+// WithAuthFrame 开启升级后认证, timeout 为等待认证帧的超时时间, 不大于 0 时使用默认值10s
+func WithAuthFrame(timeout time.Duration, fn Authenticator) Option {
+	return optionFunc(func(o *Options) {
+		o.AuthTimeout = timeout
+		o.Authenticate = fn
+	})
+}
+
+// WithDeadLetter 设置消息被丢弃时的回调
+func WithDeadLetter(fn DeadLetterHandler) Option {
+	return optionFunc(func(o *Options) {
+		o.DeadLetter = fn
+	})
+}
+
+// WithWatermarks 设置写队列高低水位及回调, low 为 0 时为 high 的一半
+func WithWatermarks(high, low int, onHigh, onLow func(conn *Connection, depth int)) Option {
+	return optionFunc(func(o *Options) {
+		o.HighWatermark = high
+		o.LowWatermark = low
+		o.OnHighWatermark = onHigh
+		o.OnLowWatermark = onLow
+	})
+}
+
+// WithBufferedWrites 开启缓冲写入, flushInterval 为自动 Flush 的间隔, 0 表示仅手动 Flush
+func WithBufferedWrites(flushInterval time.Duration) Option {
+	return optionFunc(func(o *Options) {
+		o.BufferedWrites = true
+		o.FlushInterval = flushInterval
+	})
+}
+
+// WithSocketHook 设置连接升级后对底层连接进行设置的钩子
+func WithSocketHook(hook SocketHook) Option {
+	return optionFunc(func(o *Options) {
+		o.SocketHook = hook
+	})
+}
+
+// WithIDGenerator 设置连接ID生成器, 如 NewULID().Next
+func WithIDGenerator(gen IDGenerator) Option {
+	return optionFunc(func(o *Options) {
+		o.IDGenerator = gen
+	})
+}
+
+// WithInbound 设置入站拦截器, 与 Open 之后调用 UseInbound 不同, 不会漏过连接开启后立即到达的消息
+func WithInbound(fns ...Interceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.Inbound = append(o.Inbound, fns...)
+	})
+}
+
+// WithOutbound 设置出站拦截器
+func WithOutbound(fns ...Interceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.Outbound = append(o.Outbound, fns...)
+	})
+}
@@ -0,0 +1,51 @@
+package gows
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	conn, err := New(&Options{InChanSize: 8}, WithOutChanSize(16), WithHeartbeat(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cap(conn.inChan) != 8 || cap(conn.outChan) != 16 {
+		t.Fatalf("got chan sizes %d/%d, want 8/16", cap(conn.inChan), cap(conn.outChan))
+	}
+	if conn.getHeartbeatInterval() != 10*time.Second {
+		t.Fatalf("got heartbeat interval %v, want 10s", conn.getHeartbeatInterval())
+	}
+}
+
+func TestNewInvalidOption(t *testing.T) {
+	if _, err := New(WithInChanSize(-1)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+	conn := NewConnection(WithInChanSize(-1))
+	if cap(conn.inChan) != DefaultInChanSize {
+		t.Fatalf("got in chan size %d, want default %d", cap(conn.inChan), DefaultInChanSize)
+	}
+}
+
+func TestWriteBufferPool(t *testing.T) {
+	pool := &sync.Pool{}
+	client := newTestServer(t, WithWriteBufferPool(pool), func(conn *Connection) {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("pooled")})
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "pooled" {
+		t.Fatalf("got %q, want pooled", data)
+	}
+	if _, err = New(WithBufferSizes(-1, 0)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+}
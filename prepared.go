@@ -0,0 +1,108 @@
+package gows
+
+import (
+	"crypto/sha256"
+	"github.com/gorilla/websocket"
+	"sync"
+	"time"
+)
+
+// DefaultPreparedCacheWindow 默认预处理帧缓存时间
+const DefaultPreparedCacheWindow = time.Second
+
+// PreparedMessage 预处理的消息, 帧只编码一次, 开启压缩时每种压缩配置也只压缩一次,
+// 适合将同一份数据发送给大量连接. 连接安装了出站拦截器(如 Encryptor、Signer)时,
+// 预处理的帧会被忽略, 原始消息照常经过拦截器后再编码, 以保证拦截器不被绕过
+type PreparedMessage struct {
+	// msg 原始消息
+	msg *Message
+	// prepared 预处理的帧
+	prepared *websocket.PreparedMessage
+}
+
+// NewPreparedMessage 新建 PreparedMessage实例
+func NewPreparedMessage(msg *Message) (*PreparedMessage, error) {
+	prepared, err := websocket.NewPreparedMessage(msg.MessageType, msg.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedMessage{msg: msg, prepared: prepared}, nil
+}
+
+// WritePrepared 写入预处理的消息, 写入时连接有出站拦截器则退化为普通写入
+func (c *Connection) WritePrepared(pm *PreparedMessage) error {
+	return c.enqueue(c.outChan, pendingMessage{msg: pm.msg, size: len(pm.msg.Data), prepared: pm.prepared})
+}
+
+// preparedEntry 预处理帧缓存项
+type preparedEntry struct {
+	// pm 预处理的消息
+	pm *PreparedMessage
+	// expires 过期时间
+	expires time.Time
+}
+
+// PreparedCache 在时间窗口内识别相同的消息并复用预处理的帧, 适合排行榜、行情等
+// 将同一份快照发送给所有连接的场景, 调用方无需自行判断两次广播的内容是否相同
+type PreparedCache struct {
+	// mutex 保护 entries 与 nextPrune
+	mutex sync.Mutex
+	// window 缓存时间
+	window time.Duration
+	// entries 消息类型与内容摘要到预处理帧的映射
+	entries map[[sha256.Size + 1]byte]*preparedEntry
+	// nextPrune 下次清理过期帧的时间
+	nextPrune time.Time
+}
+
+// NewPreparedCache 新建 PreparedCache实例, window 为缓存时间, 不大于 0 时使用默认值1s
+func NewPreparedCache(window time.Duration) *PreparedCache {
+	if window <= 0 {
+		window = DefaultPreparedCacheWindow
+	}
+	return &PreparedCache{
+		window:  window,
+		entries: make(map[[sha256.Size + 1]byte]*preparedEntry),
+	}
+}
+
+// Get 获取消息的预处理帧, 窗口内已有相同消息时直接复用
+func (p *PreparedCache) Get(msg *Message) (*PreparedMessage, error) {
+	var key [sha256.Size + 1]byte
+	key[0] = byte(msg.MessageType)
+	sum := sha256.Sum256(msg.Data)
+	copy(key[1:], sum[:])
+	now := time.Now()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if e, ok := p.entries[key]; ok && now.Before(e.expires) {
+		return e.pm, nil
+	}
+	p.prune(now)
+	pm, err := NewPreparedMessage(msg.Clone())
+	if err != nil {
+		return nil, err
+	}
+	p.entries[key] = &preparedEntry{pm: pm, expires: now.Add(p.window)}
+	return pm, nil
+}
+
+// Len 获取缓存的预处理帧数量
+func (p *PreparedCache) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.entries)
+}
+
+// prune 清理过期的预处理帧, 每个缓存时间最多清理一次, 调用方需持有 mutex
+func (p *PreparedCache) prune(now time.Time) {
+	if now.Before(p.nextPrune) {
+		return
+	}
+	for k, e := range p.entries {
+		if !now.Before(e.expires) {
+			delete(p.entries, k)
+		}
+	}
+	p.nextPrune = now.Add(p.window)
+}
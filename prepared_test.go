@@ -0,0 +1,67 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreparedCache(t *testing.T) {
+	cache := NewPreparedCache(time.Minute)
+	a, _ := cache.Get(&Message{MessageType: TextMessage, Data: []byte("snapshot")})
+	b, _ := cache.Get(&Message{MessageType: TextMessage, Data: []byte("snapshot")})
+	c, _ := cache.Get(&Message{MessageType: BinaryMessage, Data: []byte("snapshot")})
+	if a != b {
+		t.Fatal("identical payload not reused")
+	}
+	if a == c {
+		t.Fatal("different message type reused")
+	}
+	if n := cache.Len(); n != 2 {
+		t.Fatalf("got %d entries, want 2", n)
+	}
+}
+
+func TestWritePrepared(t *testing.T) {
+	pm, err := NewPreparedMessage(&Message{MessageType: TextMessage, Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestServer(t, WithCompression(0), func(conn *Connection) {
+		_ = conn.WritePrepared(pm)
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("got %q, want hello", data)
+	}
+}
+
+func TestWritePreparedIntercepted(t *testing.T) {
+	pm, err := NewPreparedMessage(&Message{MessageType: TextMessage, Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.UseOutbound(func(conn *Connection, msg *Message) (*Message, error) {
+			out := msg.Clone()
+			out.Data = append([]byte("intercepted:"), msg.Data...)
+			return out, nil
+		})
+		_ = conn.WritePrepared(pm)
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "intercepted:hello" {
+		t.Fatalf("got %q, want the outbound interceptor applied", data)
+	}
+}
@@ -0,0 +1,64 @@
+package gows
+
+import "sync/atomic"
+
+// QueueDepths 获取读写队列中的消息数, out 包含紧急消息与主题队列中的消息.
+// 可用于在消息开始丢弃前观察到背压的积累
+func (c *Connection) QueueDepths() (in, out int) {
+	return len(c.inChan), c.outDepth()
+}
+
+// HighWaterMarks 获取读写队列消息数的历史最大值
+func (c *Connection) HighWaterMarks() (in, out int) {
+	return int(atomic.LoadInt64(&c.inHighWater)), int(atomic.LoadInt64(&c.outHighWater))
+}
+
+// ResetHighWaterMarks 将读写队列消息数的历史最大值重置为当前值, 便于按周期采集
+func (c *Connection) ResetHighWaterMarks() {
+	in, out := c.QueueDepths()
+	atomic.StoreInt64(&c.inHighWater, int64(in))
+	atomic.StoreInt64(&c.outHighWater, int64(out))
+}
+
+// outDepth 写队列中的消息数
+func (c *Connection) outDepth() int {
+	return len(c.outChan) + len(c.urgentChan) + int(atomic.LoadInt64(&c.topics.count))
+}
+
+// updateHighWater 更新历史最大值
+func updateHighWater(mark *int64, depth int) {
+	for {
+		old := atomic.LoadInt64(mark)
+		if int64(depth) <= old || atomic.CompareAndSwapInt64(mark, old, int64(depth)) {
+			return
+		}
+	}
+}
+
+// enqueued 消息入队后更新历史最大值, 越过高水位时回调 onHighWatermark
+func (c *Connection) enqueued() {
+	depth := c.outDepth()
+	updateHighWater(&c.outHighWater, depth)
+	if c.highWatermark > 0 && depth >= c.highWatermark && atomic.CompareAndSwapInt32(&c.aboveHighWatermark, 0, 1) {
+		if c.onHighWatermark != nil {
+			c.protect(func() {
+				c.onHighWatermark(c, depth)
+			})
+		}
+	}
+}
+
+// dequeued 消息出队后回落到低水位时回调 onLowWatermark
+func (c *Connection) dequeued() {
+	if atomic.LoadInt32(&c.aboveHighWatermark) == 0 {
+		return
+	}
+	depth := c.outDepth()
+	if depth <= c.lowWatermark && atomic.CompareAndSwapInt32(&c.aboveHighWatermark, 1, 0) {
+		if c.onLowWatermark != nil {
+			c.protect(func() {
+				c.onLowWatermark(c, depth)
+			})
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package gows
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueueDepths(t *testing.T) {
+	conn := NewConnection()
+	for i := 0; i < 3; i++ {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("x")})
+	}
+	_ = conn.WriteUrgent(&Message{MessageType: TextMessage, Data: []byte("u")})
+	_ = conn.WriteTopic("t", &Message{MessageType: TextMessage, Data: []byte("t")})
+	if in, out := conn.QueueDepths(); in != 0 || out != 5 {
+		t.Fatalf("got depths %d/%d, want 0/5", in, out)
+	}
+	<-conn.outChan
+	<-conn.outChan
+	if _, out := conn.HighWaterMarks(); out != 5 {
+		t.Fatalf("got out high water %d, want 5", out)
+	}
+	conn.ResetHighWaterMarks()
+	if _, out := conn.HighWaterMarks(); out != 3 {
+		t.Fatalf("got out high water %d after reset, want 3", out)
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	var events []string
+	conn := NewConnection(WithWatermarks(3, 1, func(conn *Connection, depth int) {
+		events = append(events, "high")
+	}, func(conn *Connection, depth int) {
+		events = append(events, "low")
+	}))
+	for i := 0; i < 4; i++ {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("x")})
+	}
+	for i := 0; i < 4; i++ {
+		<-conn.outChan
+		conn.dequeued()
+	}
+	if got := strings.Join(events, ","); got != "high,low" {
+		t.Fatalf("got events %q, want high,low", got)
+	}
+	if _, err := New(WithWatermarks(3, 3, nil, nil)); err == nil {
+		t.Fatal("want error for low watermark not below high")
+	}
+}
@@ -0,0 +1,87 @@
+package gows
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RBACWildcard 授权给角色的通配事件名, 表示允许所有事件
+const RBACWildcard = "*"
+
+// RBAC 基于角色的事件访问控制, 可作为入站拦截器使用.
+// 角色与允许的事件可在代码中通过 Allow 声明, 也可通过 Load 从配置加载
+type RBAC struct {
+	// AllowNonEnvelope 是否放行非信封格式的消息, 默认拒绝
+	AllowNonEnvelope bool
+	// mutex 保护 grants
+	mutex sync.RWMutex
+	// roles 获取连接的角色
+	roles func(conn *Connection) []string
+	// grants 角色到允许事件的映射
+	grants map[string]map[string]struct{}
+}
+
+// NewRBAC 新建 RBAC实例, roles 用于获取连接已认证的角色
+func NewRBAC(roles func(conn *Connection) []string) *RBAC {
+	return &RBAC{
+		roles:  roles,
+		grants: make(map[string]map[string]struct{}),
+	}
+}
+
+// Allow 允许角色发送指定事件, 事件名为 RBACWildcard 时允许所有事件
+func (r *RBAC) Allow(role string, events ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	grant, ok := r.grants[role]
+	if !ok {
+		grant = make(map[string]struct{})
+		r.grants[role] = grant
+	}
+	for _, event := range events {
+		grant[event] = struct{}{}
+	}
+}
+
+// Load 从配置加载授权, grants 为角色到允许事件的映射, 如 JSON {"admin": ["*"], "user": ["chat"]}
+func (r *RBAC) Load(grants map[string][]string) {
+	for role, events := range grants {
+		r.Allow(role, events...)
+	}
+}
+
+// Allowed 判断连接是否允许发送事件
+func (r *RBAC) Allowed(conn *Connection, event string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, role := range r.roles(conn) {
+		grant := r.grants[role]
+		if _, ok := grant[event]; ok {
+			return true
+		}
+		if _, ok := grant[RBACWildcard]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Intercept 校验消息的事件权限, 可作为入站拦截器使用. 未授权时向客户端回复错误信封并丢弃消息.
+// 非信封格式的消息无法校验事件, 除非设置 AllowNonEnvelope, 否则同样拒绝
+func (r *RBAC) Intercept(conn *Connection, msg *Message) (*Message, error) {
+	e, err := DecodeMessageEnvelope(msg)
+	if err != nil {
+		if r.AllowNonEnvelope {
+			return msg, nil
+		}
+		err = fmt.Errorf("%w: non-envelope message", ErrForbidden)
+		_ = conn.WriteError(nil, ErrorCodeForbidden, err.Error())
+		return nil, err
+	}
+	if !r.Allowed(conn, e.Event) {
+		err = fmt.Errorf("%w: event %q", ErrForbidden, e.Event)
+		_ = conn.WriteError(e, ErrorCodeForbidden, err.Error())
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,80 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBACIntercept(t *testing.T) {
+	rbac := NewRBAC(func(conn *Connection) []string {
+		return []string{conn.GetUserID()}
+	})
+	rbac.Load(map[string][]string{"admin": {RBACWildcard}})
+	rbac.Allow("user", "chat")
+	conn := NewConnection()
+	for _, c := range []struct {
+		role  string
+		event string
+		allow bool
+	}{
+		{"user", "chat", true},
+		{"user", "delete", false},
+		{"admin", "delete", true},
+		{"guest", "chat", false},
+	} {
+		conn.SetUserID(c.role)
+		e, _ := NewEnvelope(c.event, nil)
+		msg, _ := e.Message()
+		got, err := rbac.Intercept(conn, msg)
+		if c.allow {
+			if err != nil || got == nil {
+				t.Fatalf("%s %s: got %v, want allowed", c.role, c.event, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrForbidden) || got != nil {
+			t.Fatalf("%s %s: got %v, want ErrForbidden", c.role, c.event, err)
+		}
+		reply, _ := DecodeEnvelope((<-conn.outChan).msg.Data)
+		var payload ErrorPayload
+		if err = reply.Bind(&payload); err != nil || payload.Code != ErrorCodeForbidden || payload.Ref != e.ID {
+			t.Fatalf("%s %s: got error reply %+v", c.role, c.event, payload)
+		}
+	}
+}
+
+func TestRBACInterceptBinaryEnvelope(t *testing.T) {
+	rbac := NewRBAC(func(conn *Connection) []string {
+		return []string{conn.GetUserID()}
+	})
+	rbac.Allow("user", "chat")
+	conn := NewConnection()
+	conn.SetUserID("user")
+	e, _ := NewEnvelope("delete", nil)
+	msg, _ := e.BinaryMessage()
+	if got, err := rbac.Intercept(conn, msg); !errors.Is(err, ErrForbidden) || got != nil {
+		t.Fatalf("got %v, want binary envelope denied with ErrForbidden", err)
+	}
+}
+
+func TestRBACInterceptNonEnvelope(t *testing.T) {
+	rbac := NewRBAC(func(conn *Connection) []string {
+		return []string{conn.GetUserID()}
+	})
+	rbac.Allow("admin", RBACWildcard)
+	conn := NewConnection()
+	conn.SetUserID("admin")
+	msg := &Message{MessageType: TextMessage, Data: []byte("raw")}
+	if got, err := rbac.Intercept(conn, msg); !errors.Is(err, ErrForbidden) || got != nil {
+		t.Fatalf("got %v, want non-envelope message denied with ErrForbidden", err)
+	}
+	reply, _ := DecodeEnvelope((<-conn.outChan).msg.Data)
+	var payload ErrorPayload
+	if err := reply.Bind(&payload); err != nil || payload.Code != ErrorCodeForbidden {
+		t.Fatalf("got error reply %+v", payload)
+	}
+	rbac.AllowNonEnvelope = true
+	if got, err := rbac.Intercept(conn, msg); err != nil || got != msg {
+		t.Fatalf("got %v, want non-envelope message allowed", err)
+	}
+}
@@ -0,0 +1,179 @@
+package gows
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// ReceiptEvent 回执帧的事件名
+	ReceiptEvent = "receipt"
+
+	// DefaultReceiptTTL 默认回执状态保留时间
+	DefaultReceiptTTL = time.Hour
+)
+
+// ReceiptStatus 消息回执状态
+type ReceiptStatus int
+
+const (
+	// ReceiptUnknown 未跟踪或已过期的消息
+	ReceiptUnknown ReceiptStatus = iota
+	// ReceiptSent 已发送, 尚未收到回执
+	ReceiptSent
+	// ReceiptDelivered 客户端已收到
+	ReceiptDelivered
+	// ReceiptRead 客户端已读
+	ReceiptRead
+)
+
+// String 回执状态的名称, 与回执帧中的 status 一致
+func (s ReceiptStatus) String() string {
+	switch s {
+	case ReceiptSent:
+		return "sent"
+	case ReceiptDelivered:
+		return "delivered"
+	case ReceiptRead:
+		return "read"
+	}
+	return "unknown"
+}
+
+// ReceiptPayload 回执帧的消息体
+type ReceiptPayload struct {
+	// Ref 回执对应的消息ID
+	Ref string `json:"ref"`
+	// Status 回执状态, delivered 或 read
+	Status string `json:"status"`
+}
+
+// receiptEntry 一条消息的回执状态
+type receiptEntry struct {
+	// status 当前状态
+	status ReceiptStatus
+	// sentAt 发送时间
+	sentAt time.Time
+	// owner 接收方, 已绑定用户时为用户ID, 否则为连接ID
+	owner string
+}
+
+// ReceiptTracker 消息回执跟踪. 通过 Send 发送的信封记录为已发送, 客户端以事件名为 receipt、
+// 消息体为 ReceiptPayload 的信封回复 delivered 或 read 回执, 由 Intercept 拦截并更新状态.
+// 状态只会前进, 超过保留时间后不再跟踪. 回执只对发往同一用户(未绑定用户时为同一连接)的消息生效,
+// 客户端无法确认发给他人的消息.
+type ReceiptTracker struct {
+	// ttl 状态保留时间
+	ttl time.Duration
+	// onReceipt 收到回执时的回调
+	onReceipt func(conn *Connection, ref string, status ReceiptStatus)
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// entries 消息ID及其回执状态
+	entries map[string]*receiptEntry
+	// nextPrune 下次清理过期状态的时间
+	nextPrune time.Time
+	// clock 时钟, 使用最近一次发送的连接的时钟
+	clock Clock
+}
+
+// NewReceiptTracker 新建 ReceiptTracker实例, ttl 为 0 时使用 DefaultReceiptTTL, onReceipt 可为 nil.
+func NewReceiptTracker(ttl time.Duration, onReceipt func(conn *Connection, ref string, status ReceiptStatus)) *ReceiptTracker {
+	if ttl <= 0 {
+		ttl = DefaultReceiptTTL
+	}
+	return &ReceiptTracker{ttl: ttl, onReceipt: onReceipt, entries: make(map[string]*receiptEntry), clock: realClock{}}
+}
+
+// Send 写入信封并记录为已发送
+func (t *ReceiptTracker) Send(conn *Connection, e *Envelope) error {
+	now := conn.clock.Now()
+	t.mutex.Lock()
+	t.clock = conn.clock
+	t.prune(now)
+	t.entries[e.ID] = &receiptEntry{status: ReceiptSent, sentAt: now, owner: receiptOwner(conn)}
+	t.mutex.Unlock()
+	if err := conn.WriteEnvelope(e); err != nil {
+		t.mutex.Lock()
+		delete(t.entries, e.ID)
+		t.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Status 获取消息的回执状态
+func (t *ReceiptTracker) Status(id string) ReceiptStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if entry, ok := t.entries[id]; ok && t.clock.Now().Sub(entry.sentAt) < t.ttl {
+		return entry.status
+	}
+	return ReceiptUnknown
+}
+
+// Intercept 入站拦截器, 拦截回执帧并更新状态, 回执帧不会被 Receive 读取. 其他消息原样放行
+func (t *ReceiptTracker) Intercept(conn *Connection, msg *Message) (*Message, error) {
+	e, err := DecodeMessageEnvelope(msg)
+	if err != nil || e.Event != ReceiptEvent {
+		return msg, nil
+	}
+	var payload ReceiptPayload
+	if err = e.Bind(&payload); err != nil {
+		_ = conn.WriteError(e, ErrorCodeInvalidPayload, err.Error())
+		return nil, nil
+	}
+	var status ReceiptStatus
+	switch payload.Status {
+	case ReceiptDelivered.String():
+		status = ReceiptDelivered
+	case ReceiptRead.String():
+		status = ReceiptRead
+	default:
+		_ = conn.WriteError(e, ErrorCodeInvalidPayload, "unknown receipt status "+payload.Status)
+		return nil, nil
+	}
+	if t.update(conn, payload.Ref, status) && t.onReceipt != nil {
+		conn.protect(func() {
+			t.onReceipt(conn, payload.Ref, status)
+		})
+	}
+	return nil, nil
+}
+
+// update 更新 conn 回复的状态, 未跟踪、已过期、不属于 conn 的消息或状态未前进时返回 false
+func (t *ReceiptTracker) update(conn *Connection, id string, status ReceiptStatus) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry, ok := t.entries[id]
+	if !ok || entry.owner != receiptOwner(conn) || status <= entry.status {
+		return false
+	}
+	if conn.clock.Now().Sub(entry.sentAt) >= t.ttl {
+		delete(t.entries, id)
+		return false
+	}
+	entry.status = status
+	return true
+}
+
+// receiptOwner 获取连接作为接收方的标识, 已绑定用户时为用户ID, 否则为连接ID
+func receiptOwner(conn *Connection) string {
+	if userID := conn.GetUserID(); userID != "" {
+		return "user:" + userID
+	}
+	return "conn:" + conn.GetConnID()
+}
+
+// prune 清理过期状态, 调用方需持有 mutex
+func (t *ReceiptTracker) prune(now time.Time) {
+	if now.Before(t.nextPrune) {
+		return
+	}
+	for id, entry := range t.entries {
+		if now.Sub(entry.sentAt) >= t.ttl {
+			delete(t.entries, id)
+		}
+	}
+	t.nextPrune = now.Add(t.ttl)
+}
@@ -0,0 +1,81 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReceiptTracker(t *testing.T) {
+	receipts := make(chan ReceiptStatus, 4)
+	tracker := NewReceiptTracker(time.Minute, func(conn *Connection, ref string, status ReceiptStatus) {
+		receipts <- status
+	})
+	sent := make(chan string, 1)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.UseInbound(tracker.Intercept)
+		e, _ := NewEnvelope("chat", "hi")
+		if err := tracker.Send(conn, e); err != nil {
+			t.Error(err)
+		}
+		sent <- e.ID
+		msg, err := conn.Receive()
+		if err == nil && string(msg.Data) != "other" {
+			t.Errorf("got %q, want receipts consumed by interceptor", msg.Data)
+		}
+	})
+	defer client.Close()
+	id := <-sent
+	if got := tracker.Status(id); got != ReceiptSent {
+		t.Fatalf("got %v, want sent", got)
+	}
+	for _, status := range []string{"delivered", "delivered", "read"} {
+		e, _ := NewEnvelope(ReceiptEvent, &ReceiptPayload{Ref: id, Status: status})
+		data, _ := e.Encode()
+		if err := client.WriteMessage(TextMessage, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = client.WriteMessage(TextMessage, []byte("other"))
+	for _, want := range []ReceiptStatus{ReceiptDelivered, ReceiptRead} {
+		select {
+		case got := <-receipts:
+			if got != want {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	if got := tracker.Status(id); got != ReceiptRead {
+		t.Fatalf("got %v, want read", got)
+	}
+	if got := tracker.Status("missing"); got != ReceiptUnknown {
+		t.Fatalf("got %v, want unknown", got)
+	}
+}
+
+func TestReceiptTrackerScope(t *testing.T) {
+	tracker := NewReceiptTracker(time.Minute, nil)
+	owner, other := NewConnection(), NewConnection()
+	owner.SetUserID("u1")
+	e, _ := NewEnvelope("chat", "hi")
+	if err := tracker.Send(owner, e); err != nil {
+		t.Fatal(err)
+	}
+	receipt := func(conn *Connection) {
+		r, _ := NewEnvelope(ReceiptEvent, &ReceiptPayload{Ref: e.ID, Status: "read"})
+		msg, _ := r.Message()
+		_, _ = tracker.Intercept(conn, msg)
+	}
+	receipt(other)
+	if got := tracker.Status(e.ID); got != ReceiptSent {
+		t.Fatalf("got %v, want receipt from another connection ignored", got)
+	}
+	// 回执的有效期按连接的时钟判断
+	late := NewConnection(WithClock(skewClock{skew: time.Hour}))
+	late.SetUserID("u1")
+	receipt(late)
+	if got := tracker.Status(e.ID); got != ReceiptUnknown {
+		t.Fatalf("got %v, want expired receipt rejected", got)
+	}
+}
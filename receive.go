@@ -0,0 +1,73 @@
+package gows
+
+import "time"
+
+// timeoutError 超时错误, 实现 net.Error
+type timeoutError struct{}
+
+// Error 错误描述
+func (timeoutError) Error() string {
+	return "receive timeout"
+}
+
+// Timeout 是否为超时错误
+func (timeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary 是否为临时错误, 超时后可继续接收
+func (timeoutError) Temporary() bool {
+	return true
+}
+
+// ReceiveTimeout 接收数据, 超过 d 仍未收到消息时返回 ErrReceiveTimeout, d 不大于 0 时等同于 Receive
+func (c *Connection) ReceiveTimeout(d time.Duration) (*Message, error) {
+	if d <= 0 {
+		return c.Receive()
+	}
+	timer := c.clock.NewTimer(d)
+	defer timer.Stop()
+	return c.receive(timer.C())
+}
+
+// ReceiveBatch 批量接收数据, 等待第一条消息最多 wait 后, 再取出读队列中已有的消息, 最多返回 max 条,
+// 便于处理突发消息时分摊处理开销. wait 不大于 0 时一直等待第一条消息.
+// 超时返回 ErrReceiveTimeout, 连接已关闭时返回 ErrConnClose.
+// 零拷贝接收模式下返回的消息均为拷贝, 且读协程需等待上一条消息释放后才读取下一条, 读队列中通常至多有一条消息,
+// 因此每批一般只有一条消息, 不宜与零拷贝接收模式同时使用
+func (c *Connection) ReceiveBatch(max int, wait time.Duration) ([]*Message, error) {
+	if max <= 0 {
+		return nil, nil
+	}
+	msg, err := c.ReceiveTimeout(wait)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]*Message, 0, max)
+	for {
+		if c.zeroCopy {
+			msg = msg.Clone()
+		}
+		batch = append(batch, msg)
+		if len(batch) == max {
+			return batch, nil
+		}
+		var ok bool
+		if msg, ok = c.tryReceive(); !ok {
+			return batch, nil
+		}
+	}
+}
+
+// tryReceive 不等待地接收数据, 读队列为空时返回 false
+func (c *Connection) tryReceive() (*Message, bool) {
+	c.releaseReceived()
+	select {
+	case msg := <-c.inChan:
+		c.releaseMemory(len(msg.Data))
+		c.markReceived()
+		return msg, true
+	default:
+		return nil, false
+	}
+}
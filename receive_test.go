@@ -0,0 +1,84 @@
+package gows
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReceiveTimeout(t *testing.T) {
+	results := make(chan error, 2)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		_, err := conn.ReceiveTimeout(20 * time.Millisecond)
+		results <- err
+		msg, err := conn.ReceiveTimeout(time.Second)
+		if err == nil && string(msg.Data) != "late" {
+			t.Errorf("got %q, want late", msg.Data)
+		}
+		results <- err
+	})
+	defer client.Close()
+	err := <-results
+	var netErr net.Error
+	if err != ErrReceiveTimeout || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("got %v, want ErrReceiveTimeout implementing net.Error", err)
+	}
+	if err = client.WriteMessage(TextMessage, []byte("late")); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-results; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReceiveBatch(t *testing.T) {
+	type result struct {
+		batch []*Message
+		err   error
+	}
+	batches := make(chan result, 4)
+	ready := make(chan struct{})
+	client := newTestServer(t, nil, func(conn *Connection) {
+		receive := func(wait time.Duration) {
+			batch, err := conn.ReceiveBatch(4, wait)
+			batches <- result{batch, err}
+		}
+		receive(20 * time.Millisecond)
+		<-ready
+		// 等待读协程将客户端发送的消息全部放入读队列
+		for len(conn.inChan) < 6 {
+			time.Sleep(time.Millisecond)
+		}
+		receive(time.Second)
+		receive(time.Second)
+		_ = conn.Close()
+		receive(time.Second)
+	})
+	defer client.Close()
+	if got := <-batches; len(got.batch) != 0 || got.err != ErrReceiveTimeout {
+		t.Fatalf("got %d messages, %v, want empty batch with ErrReceiveTimeout", len(got.batch), got.err)
+	}
+	for i := 0; i < 6; i++ {
+		if err := client.WriteMessage(TextMessage, []byte{byte('a' + i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(ready)
+	var got string
+	for _, want := range []int{4, 2} {
+		r := <-batches
+		if r.err != nil || len(r.batch) != want {
+			t.Fatalf("got %d messages, %v, want %d", len(r.batch), r.err, want)
+		}
+		for _, msg := range r.batch {
+			got += string(msg.Data)
+		}
+	}
+	if got != "abcdef" {
+		t.Fatalf("got %q, want abcdef", got)
+	}
+	if r := <-batches; len(r.batch) != 0 || r.err != ErrConnClose {
+		t.Fatalf("got %d messages, %v, want ErrConnClose after close", len(r.batch), r.err)
+	}
+}
@@ -0,0 +1,34 @@
+package gows
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// protect 执行用户回调, 回调发生 panic 时关闭连接而不是让进程崩溃
+func (c *Connection) protect(fn func()) {
+	defer c.recoverPanic()
+	fn()
+}
+
+// recoverPanic 捕获 panic, 记录日志并回调 onPanic, 然后关闭连接. 须通过 defer 调用
+func (c *Connection) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	c.logger.Printf("gows: connection %s panic: %v\n%s", c.id, r, stack)
+	if c.onPanic != nil {
+		// onPanic 自身发生 panic 时只记录日志, 仍然关闭连接
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					c.logger.Printf("gows: connection %s OnPanic panic: %v", c.id, p)
+				}
+			}()
+			c.onPanic(c, r, stack)
+		}()
+	}
+	_ = c.close(fmt.Errorf("%w: %v", ErrPanic, r))
+}
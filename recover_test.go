@@ -0,0 +1,55 @@
+package gows
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"testing"
+	"time"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	panics := make(chan interface{}, 1)
+	closed := make(chan error, 1)
+	opts := &Options{
+		OnPanic: func(conn *Connection, recovered interface{}, stack []byte) { panics <- recovered },
+		OnClose: func(conn *Connection, reason error) { closed <- reason },
+		Inbound: []Interceptor{func(conn *Connection, msg *Message) (*Message, error) {
+			panic("boom")
+		}},
+	}
+	client := newTestServer(t, opts, func(conn *Connection) {
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	if err := client.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-panics:
+		if r != "boom" {
+			t.Fatalf("got %v, want boom", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnPanic not called")
+	}
+	select {
+	case reason := <-closed:
+		if !errors.Is(reason, ErrPanic) {
+			t.Fatalf("got close reason %v, want ErrPanic", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed")
+	}
+}
+
+func TestRecoverPanicInOnPanic(t *testing.T) {
+	conn := NewConnection(WithOnPanic(func(conn *Connection, recovered interface{}, stack []byte) {
+		panic("again")
+	}))
+	conn.protect(func() {
+		panic("boom")
+	})
+	if !errors.Is(conn.CloseReason(), ErrPanic) {
+		t.Fatalf("got close reason %v, want ErrPanic", conn.CloseReason())
+	}
+}
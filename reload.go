@@ -0,0 +1,122 @@
+package gows
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// Reloader 运行时热加载配置. 新配置对之后新建的连接全部生效, 对已开启的连接仅调整可安全修改的心跳检测间隔;
+// 速率限制、最大连接数等由应用自行管理的设置可在 OnReload 回调中调整.
+type Reloader struct {
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// config 当前配置
+	config Config
+	// conns 已开启的连接
+	conns map[*Connection]struct{}
+	// onReload 配置更新后的回调
+	onReload []func(cfg *Config)
+}
+
+// NewReloader 新建 Reloader实例, cfg 为初始配置, 为 nil 时使用 DefaultConfig.
+func NewReloader(cfg *Config) *Reloader {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Reloader{config: *cfg, conns: make(map[*Connection]struct{})}
+}
+
+// Config 获取当前配置的副本
+func (r *Reloader) Config() *Config {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	cfg := r.config
+	return &cfg
+}
+
+// Options 返回当前配置对应的连接配置, 用于新建连接
+func (r *Reloader) Options() *Options {
+	return r.Config().Options()
+}
+
+// OnReload 注册配置更新后的回调, 回调按注册顺序执行
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// Track 跟踪已开启的连接, 之后的配置更新将应用到该连接, 连接关闭后自动取消跟踪
+func (r *Reloader) Track(conn *Connection) {
+	r.mutex.Lock()
+	r.conns[conn] = struct{}{}
+	r.mutex.Unlock()
+	go func() {
+		<-conn.closeChan
+		r.mutex.Lock()
+		delete(r.conns, conn)
+		r.mutex.Unlock()
+	}()
+}
+
+// Apply 校验并应用新配置, 配置非法时返回 ErrInvalidOption 且保持原配置不变
+func (r *Reloader) Apply(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	old := r.config
+	r.config = *cfg
+	conns := make([]*Connection, 0, len(r.conns))
+	for conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	callbacks := r.onReload
+	r.mutex.Unlock()
+	if cfg.HeartbeatInterval > 0 && cfg.HeartbeatInterval != old.HeartbeatInterval {
+		for _, conn := range conns {
+			conn.SetHeartbeatInterval(time.Duration(cfg.HeartbeatInterval) * time.Second)
+		}
+	}
+	for _, fn := range callbacks {
+		c := *cfg
+		fn(&c)
+	}
+	return nil
+}
+
+// Watch 收到 sigs 中的信号时调用 load 加载配置并应用, sigs 通常为 syscall.SIGHUP.
+// 加载或应用失败时调用 onError, 可为 nil. 返回的函数用于停止监听
+func (r *Reloader) Watch(load func() (*Config, error), onError func(err error), sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				cfg, err := load()
+				if err == nil {
+					err = r.Apply(cfg)
+				}
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
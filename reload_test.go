@@ -0,0 +1,68 @@
+package gows
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	r := NewReloader(nil)
+	reloaded := make(chan *Config, 2)
+	r.OnReload(func(cfg *Config) { reloaded <- cfg })
+	tracked := make(chan *Connection, 1)
+	client := newTestServer(t, r.Options(), func(conn *Connection) {
+		r.Track(conn)
+		tracked <- conn
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	conn := <-tracked
+	cfg := r.Config()
+	cfg.HeartbeatInterval = 60
+	if err := r.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := conn.getHeartbeatInterval(); got != time.Minute {
+		t.Fatalf("got heartbeat interval %v, want 1m", got)
+	}
+	if got := <-reloaded; got.HeartbeatInterval != 60 {
+		t.Fatalf("got %+v in OnReload", got)
+	}
+	if r.Options().HeartbeatInterval != 60 {
+		t.Fatal("new connections do not use the reloaded config")
+	}
+	cfg.InChanSize = -1
+	if err := r.Apply(cfg); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("got %v, want ErrInvalidOption", err)
+	}
+	if r.Config().InChanSize != DefaultInChanSize {
+		t.Fatal("invalid config was applied")
+	}
+}
+
+func TestReloaderWatch(t *testing.T) {
+	r := NewReloader(nil)
+	stop := r.Watch(func() (*Config, error) {
+		cfg := DefaultConfig()
+		cfg.PingInterval = 15
+		return cfg, nil
+	}, nil, syscall.SIGHUP)
+	defer stop()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.Config().PingInterval != 15 {
+		if time.Now().After(deadline) {
+			t.Fatal("config not reloaded on SIGHUP")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,112 @@
+package gows
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const (
+	// replayNonceSize 防重放随机数长度
+	replayNonceSize = 16
+	// replayTrailerSize 防重放尾部长度, 8 字节纳秒时间戳 + 随机数
+	replayTrailerSize = 8 + replayNonceSize
+)
+
+// SignerOptions 签名可选参数
+type SignerOptions struct {
+	// ReplayWindow 防重放时间窗口, 大于 0 时签名内容中附带时间戳与随机数,
+	// 校验时拒绝时间戳偏离当前时间超过窗口或随机数在窗口内已出现过的消息, 默认不开启
+	ReplayWindow time.Duration
+}
+
+// replayGuard 记录时间窗口内已出现过的随机数
+type replayGuard struct {
+	// window 时间窗口
+	window time.Duration
+	// mutex 保护 seen 与 nextPrune
+	mutex sync.Mutex
+	// seen 随机数及其过期时间
+	seen map[string]time.Time
+	// nextPrune 下次清理过期随机数的时间
+	nextPrune time.Time
+}
+
+// newReplayGuard 新建 replayGuard实例
+func newReplayGuard(window time.Duration) *replayGuard {
+	return &replayGuard{window: window, seen: make(map[string]time.Time)}
+}
+
+// seal 为消息内容追加时间戳与随机数, 与内容一起参与签名
+func (g *replayGuard) seal(msgType int, data []byte, now time.Time) ([]byte, error) {
+	trailer := make([]byte, replayTrailerSize)
+	binary.BigEndian.PutUint64(trailer, uint64(now.UnixNano()))
+	if _, err := rand.Read(trailer[8:]); err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, 0, len(data)+1+base64.RawURLEncoding.EncodedLen(replayTrailerSize))
+	sealed = append(sealed, data...)
+	if msgType == TextMessage {
+		sealed = append(sealed, signatureSeparator)
+		sealed = append(sealed, base64.RawURLEncoding.EncodeToString(trailer)...)
+	} else {
+		sealed = append(sealed, trailer...)
+	}
+	return sealed, nil
+}
+
+// open 校验并去除时间戳与随机数, 超出时间窗口或随机数重复时返回 ErrReplayed
+func (g *replayGuard) open(msgType int, data []byte, now time.Time) ([]byte, error) {
+	var trailer []byte
+	if msgType == TextMessage {
+		i := bytes.LastIndexByte(data, signatureSeparator)
+		if i < 0 {
+			return nil, ErrReplayed
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(string(data[i+1:]))
+		if err != nil || len(decoded) != replayTrailerSize {
+			return nil, ErrReplayed
+		}
+		data, trailer = data[:i], decoded
+	} else {
+		if len(data) < replayTrailerSize {
+			return nil, ErrReplayed
+		}
+		n := len(data) - replayTrailerSize
+		data, trailer = data[:n], data[n:]
+	}
+	sent := time.Unix(0, int64(binary.BigEndian.Uint64(trailer)))
+	if d := now.Sub(sent); d > g.window || d < -g.window {
+		return nil, ErrReplayed
+	}
+	if !g.remember(string(trailer[8:]), sent.Add(g.window), now) {
+		return nil, ErrReplayed
+	}
+	return data, nil
+}
+
+// remember 记录随机数, 时间窗口内已出现过时返回 false
+func (g *replayGuard) remember(nonce string, expire, now time.Time) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if now.After(g.nextPrune) {
+		for k, t := range g.seen {
+			if now.After(t) {
+				delete(g.seen, k)
+			}
+		}
+		g.nextPrune = now.Add(g.window)
+	}
+	if _, ok := g.seen[nonce]; ok {
+		return false
+	}
+	// 时间戳早于当前时间时需保留到 sent+window, 晚于当前时间时额外覆盖时钟偏差
+	if limit := now.Add(g.window); expire.Before(limit) {
+		expire = limit
+	}
+	g.seen[nonce] = expire
+	return true
+}
@@ -0,0 +1,272 @@
+package gows
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryMaxAttempts 默认最大写入尝试次数
+	DefaultRetryMaxAttempts = 3
+
+	// DefaultRetryMaxAge 默认消息首次写入失败后的最长保留时间
+	DefaultRetryMaxAge = 30 * time.Second
+)
+
+// RetryOptions 重试队列可选参数
+type RetryOptions struct {
+	// MaxAttempts 最大写入尝试次数, 默认3
+	MaxAttempts int
+	// MaxAge 消息首次写入失败后的最长保留时间, 超时后视为投递失败, 默认30s
+	MaxAge time.Duration
+	// OnRetried 消息经重试后写入成功的回调, attempts 为此前失败的次数
+	OnRetried func(msg *Message, attempts int)
+	// OnDead 消息最终投递失败的回调
+	OnDead func(msg *Message, err error)
+}
+
+// retryEntry 等待重试的消息
+type retryEntry struct {
+	// msg 消息
+	msg *Message
+	// attempts 已失败的次数
+	attempts int
+	// firstFailed 首次失败时间
+	firstFailed time.Time
+	// err 最近一次失败原因
+	err error
+	// deadline 发送截止时间
+	deadline time.Time
+}
+
+// expired 判断消息在 now 时是否已过期
+func (e *retryEntry) expired(now time.Time) bool {
+	return !e.deadline.IsZero() && !now.Before(e.deadline)
+}
+
+// RetryQueue 写入失败消息的重试队列.
+// 底层连接写入失败后即不可再用, 因此重试发生在使用同一队列的新连接开启时, 即会话恢复后按原顺序重新写入.
+// 同一逻辑会话的前后连接应共用一个 RetryQueue.
+// 设置了 OrderKey 的消息在同一顺序键的消息等待重试期间会被暂存到队列中, 暂存不计入尝试次数.
+// 被丢弃(超过尝试次数、保留时间或截止时间)的消息不再阻塞后续消息, 因此顺序保证不包含送达保证.
+// 重试的消息在新连接的 Open 返回前放入独立的重试写队列, 写协程优先写入, 之后写入的消息排在其后.
+type RetryQueue struct {
+	// opts 配置
+	opts RetryOptions
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// entries 等待重试的消息
+	entries []*retryEntry
+	// clock 时钟, 使用该队列的连接的时钟
+	clock Clock
+	// timer 过期检查定时器
+	timer Timer
+	// timerDone 关闭时通知过期检查协程退出
+	timerDone chan struct{}
+}
+
+// NewRetryQueue 新建 RetryQueue实例.
+func NewRetryQueue(opts ...*RetryOptions) *RetryQueue {
+	q := &RetryQueue{clock: realClock{}}
+	if len(opts) > 0 && opts[0] != nil {
+		q.opts = *opts[0]
+	}
+	if q.opts.MaxAttempts <= 0 {
+		q.opts.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if q.opts.MaxAge <= 0 {
+		q.opts.MaxAge = DefaultRetryMaxAge
+	}
+	return q
+}
+
+// Len 等待重试的消息数
+func (q *RetryQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// Close 放弃所有等待重试的消息, 逐条回调 OnDead
+func (q *RetryQueue) Close() {
+	q.mutex.Lock()
+	entries := q.entries
+	q.entries = nil
+	if q.timer != nil {
+		q.timer.Stop()
+		close(q.timerDone)
+		q.timer = nil
+	}
+	q.mutex.Unlock()
+	for _, e := range entries {
+		q.dead(e)
+	}
+}
+
+// push 记录写入失败的消息, 超过尝试次数或保留时间时直接视为投递失败
+func (q *RetryQueue) push(pending pendingMessage, err error) {
+	e := &retryEntry{
+		msg:         pending.msg,
+		attempts:    pending.attempts + 1,
+		firstFailed: pending.firstFailed,
+		err:         err,
+		deadline:    pending.deadline,
+	}
+	now := q.now()
+	if e.firstFailed.IsZero() {
+		e.firstFailed = now
+	}
+	if e.expired(now) {
+		q.expired(e)
+		return
+	}
+	if e.attempts >= q.opts.MaxAttempts || now.Sub(e.firstFailed) > q.opts.MaxAge {
+		q.dead(e)
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.entries = append(q.entries, e)
+	if q.timer == nil {
+		q.startTimer()
+	} else if len(q.entries) == 1 {
+		q.timer.Reset(q.opts.MaxAge)
+	}
+}
+
+// hold 暂存与等待重试的消息顺序键相同的消息, 不计入尝试次数
+func (q *RetryQueue) hold(pending pendingMessage) {
+	e := &retryEntry{
+		msg:         pending.msg,
+		attempts:    pending.attempts,
+		firstFailed: pending.firstFailed,
+		err:         ErrOrderHeld,
+		deadline:    pending.deadline,
+	}
+	if e.firstFailed.IsZero() {
+		e.firstFailed = q.now()
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.entries = append(q.entries, e)
+	if q.timer == nil {
+		q.startTimer()
+	}
+}
+
+// holding 判断是否有顺序键为 key 的消息等待重试
+func (q *RetryQueue) holding(key string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, e := range q.entries {
+		if e.msg.OrderKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// resend 将等待重试的消息按原顺序放入连接的重试写队列, 最多放入队列容量条, 其余继续等待.
+// 重试写队列的容量不小于放入的消息数, 因此不会阻塞
+func (q *RetryQueue) resend(c *Connection) {
+	q.mutex.Lock()
+	// 连接在重新写入期间关闭时, 写队列中的消息与未写入的消息会分别回到队列, 按首次失败时间恢复原顺序
+	sort.SliceStable(q.entries, func(i, j int) bool {
+		return q.entries[i].firstFailed.Before(q.entries[j].firstFailed)
+	})
+	n := cap(c.retryChan)
+	if n > len(q.entries) {
+		n = len(q.entries)
+	}
+	entries := q.entries[:n:n]
+	q.entries = append([]*retryEntry(nil), q.entries[n:]...)
+	q.mutex.Unlock()
+	for _, e := range entries {
+		now := q.now()
+		if now.Sub(e.firstFailed) > q.opts.MaxAge {
+			q.dead(e)
+			continue
+		}
+		if e.expired(now) {
+			q.expired(e)
+			continue
+		}
+		_ = c.enqueue(c.retryChan, pendingMessage{msg: e.msg, size: len(e.msg.Data), attempts: e.attempts, firstFailed: e.firstFailed, deadline: e.deadline})
+	}
+}
+
+// delivered 消息写入成功
+func (q *RetryQueue) delivered(pending pendingMessage) {
+	if pending.attempts > 0 && q.opts.OnRetried != nil {
+		q.opts.OnRetried(pending.msg, pending.attempts)
+	}
+}
+
+// setClock 使用连接的时钟
+func (q *RetryQueue) setClock(clock Clock) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.clock = clock
+}
+
+// now 当前时间
+func (q *RetryQueue) now() time.Time {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.clock.Now()
+}
+
+// startTimer 启动过期检查定时器, 调用方需持有 mutex
+func (q *RetryQueue) startTimer() {
+	timer, done := q.clock.NewTimer(q.opts.MaxAge), make(chan struct{})
+	q.timer, q.timerDone = timer, done
+	go func() {
+		for {
+			select {
+			case <-timer.C():
+				q.expire()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// expire 清理超过保留时间的消息
+func (q *RetryQueue) expire() {
+	q.mutex.Lock()
+	now := q.clock.Now()
+	var expired []*retryEntry
+	kept := q.entries[:0]
+	for _, e := range q.entries {
+		if now.Sub(e.firstFailed) >= q.opts.MaxAge {
+			expired = append(expired, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	q.entries = kept
+	if len(kept) > 0 && q.timer != nil {
+		q.timer.Reset(q.opts.MaxAge - now.Sub(kept[0].firstFailed))
+	}
+	q.mutex.Unlock()
+	for _, e := range expired {
+		q.dead(e)
+	}
+}
+
+// dead 消息投递失败
+func (q *RetryQueue) dead(e *retryEntry) {
+	if q.opts.OnDead != nil {
+		q.opts.OnDead(e.msg, fmt.Errorf("%w after %d attempts: %v", ErrRetryExhausted, e.attempts, e.err))
+	}
+}
+
+// expired 消息已超过发送截止时间
+func (q *RetryQueue) expired(e *retryEntry) {
+	if q.opts.OnDead != nil {
+		q.opts.OnDead(e.msg, ErrMessageExpired)
+	}
+}
@@ -0,0 +1,128 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryQueueResend(t *testing.T) {
+	retried := make(chan int, 1)
+	q := NewRetryQueue(&RetryOptions{
+		OnRetried: func(msg *Message, attempts int) { retried <- attempts },
+	})
+	q.push(pendingMessage{msg: &Message{MessageType: TextMessage, Data: []byte("hello")}}, errors.New("broken pipe"))
+	client := newTestServer(t, WithRetryQueue(q), func(conn *Connection) {
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("got %q, want hello", data)
+	}
+	select {
+	case attempts := <-retried:
+		if attempts != 1 {
+			t.Fatalf("got %d attempts, want 1", attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnRetried not called")
+	}
+}
+
+func TestRetryQueueDead(t *testing.T) {
+	dead := make(chan error, 2)
+	q := NewRetryQueue(&RetryOptions{
+		MaxAttempts: 2,
+		MaxAge:      50 * time.Millisecond,
+		OnDead:      func(msg *Message, err error) { dead <- err },
+	})
+	q.push(pendingMessage{msg: &Message{}, attempts: 1}, errors.New("broken pipe"))
+	q.push(pendingMessage{msg: &Message{}}, errors.New("broken pipe"))
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-dead:
+			if !errors.Is(err, ErrRetryExhausted) {
+				t.Fatalf("got %v, want ErrRetryExhausted", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not declared dead", i)
+		}
+	}
+	if q.Len() != 0 {
+		t.Fatalf("got %d entries, want 0", q.Len())
+	}
+}
+
+func TestRetryQueueOrderKey(t *testing.T) {
+	q := NewRetryQueue()
+	q.push(pendingMessage{msg: &Message{MessageType: TextMessage, Data: []byte("m1"), OrderKey: "chat-1"}}, errors.New("broken pipe"))
+	// 同一顺序键的消息在 m1 等待重试期间被暂存, 不经过底层连接
+	conn := NewConnection(WithRetryQueue(q))
+	m2 := &Message{MessageType: TextMessage, Data: []byte("m2"), OrderKey: "chat-1"}
+	if err := conn.write(pendingMessage{msg: m2}); err != ErrOrderHeld {
+		t.Fatalf("got %v, want ErrOrderHeld", err)
+	}
+	if q.Len() != 2 {
+		t.Fatalf("got %d entries, want 2", q.Len())
+	}
+	client := newTestServer(t, WithRetryQueue(q), func(conn *Connection) {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte("m3"), OrderKey: "chat-1"})
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"m1", "m2", "m3"} {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("got %q, want %q", data, want)
+		}
+	}
+}
+
+func TestRetryQueueResendFullQueue(t *testing.T) {
+	q := NewRetryQueue()
+	for _, data := range []string{"m1", "m2", "m3"} {
+		q.push(pendingMessage{msg: &Message{MessageType: TextMessage, Data: []byte(data)}}, errors.New("broken pipe"))
+	}
+	// 流控额度为 0 时写协程不消费写队列, 重新入队不能阻塞 Open
+	opened := make(chan struct{})
+	client := newTestServer(t, &Options{RetryQueue: q, OutChanSize: 1, FlowControl: true}, func(conn *Connection) {
+		close(opened)
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("Open blocked on a full write queue")
+	}
+	if q.Len() != 0 {
+		t.Fatalf("got %d entries, want all resent", q.Len())
+	}
+}
+
+func TestRetryQueueClock(t *testing.T) {
+	dead := make(chan error, 1)
+	q := NewRetryQueue(&RetryOptions{OnDead: func(msg *Message, err error) { dead <- err }})
+	q.push(pendingMessage{msg: &Message{}}, errors.New("broken pipe"))
+	// 连接的时钟已超过保留时间, 重新写入前判定为投递失败
+	conn := NewConnection(WithClock(skewClock{skew: time.Hour}), WithRetryQueue(q))
+	conn.retryChan = make(chan pendingMessage, 1)
+	q.resend(conn)
+	select {
+	case err := <-dead:
+		if !errors.Is(err, ErrRetryExhausted) {
+			t.Fatalf("got %v, want ErrRetryExhausted", err)
+		}
+	default:
+		t.Fatal("message resent past MaxAge on the connection clock")
+	}
+}
@@ -0,0 +1,201 @@
+package gows
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema JSON Schema 的常用子集, 支持 type、properties、required、additionalProperties、
+// items、enum、minimum、maximum、minLength、maxLength 关键字.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// ParseSchema 解析 JSON 格式的 Schema
+func ParseSchema(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SchemaError 校验失败的位置与原因
+type SchemaError struct {
+	// Path 校验失败的字段路径, 如 $.user.name
+	Path string
+	// Reason 失败原因
+	Reason string
+}
+
+// Error 实现 error 接口
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrSchemaValidation, e.Path, e.Reason)
+}
+
+// Unwrap 支持 errors.Is(err, ErrSchemaValidation)
+func (e *SchemaError) Unwrap() error {
+	return ErrSchemaValidation
+}
+
+// Validate 校验 JSON 解码后的值, 失败时返回 *SchemaError
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate("$", v)
+}
+
+// validate 递归校验
+func (s *Schema) validate(path string, v interface{}) error {
+	fail := func(format string, args ...interface{}) error {
+		return &SchemaError{Path: path, Reason: fmt.Sprintf(format, args...)}
+	}
+	if s.Type != "" && !matchType(s.Type, v) {
+		return fail("expected %s, got %s", s.Type, typeOf(v))
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("value not in enum")
+		}
+	}
+	switch val := v.(type) {
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			return fail("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			return fail("must be <= %v", *s.Maximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fail("length must be >= %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("length must be <= %d", *s.MaxLength)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return fail("missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fail("unexpected property %q", k)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+k, val[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchType 判断值是否符合 Schema 类型
+func matchType(t string, v interface{}) bool {
+	if t == "integer" {
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return typeOf(v) == t
+}
+
+// typeOf 返回 JSON 解码后值的 Schema 类型名
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// SchemaValidator 按事件类型校验信封消息体的入站拦截器.
+// 未注册 Schema 的事件及非信封格式的消息直接放行.
+type SchemaValidator struct {
+	mutex   sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewSchemaValidator 新建 SchemaValidator实例.
+func NewSchemaValidator() *SchemaValidator {
+	return &SchemaValidator{schemas: make(map[string]*Schema)}
+}
+
+// Register 注册事件的 Schema
+func (v *SchemaValidator) Register(event string, schema *Schema) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.schemas[event] = schema
+}
+
+// Intercept 校验消息, 可作为入站拦截器使用. 校验失败时向客户端回复错误信封并丢弃消息
+func (v *SchemaValidator) Intercept(conn *Connection, msg *Message) (*Message, error) {
+	e, err := DecodeMessageEnvelope(msg)
+	if err != nil {
+		return msg, nil
+	}
+	v.mutex.RLock()
+	schema, ok := v.schemas[e.Event]
+	v.mutex.RUnlock()
+	if !ok {
+		return msg, nil
+	}
+	var payload interface{}
+	if len(e.Payload) > 0 {
+		if err = json.Unmarshal(e.Payload, &payload); err != nil {
+			return nil, err
+		}
+	}
+	if err = schema.Validate(payload); err != nil {
+		_ = conn.WriteError(e, ErrorCodeInvalidPayload, err.Error())
+		return nil, err
+	}
+	return msg, nil
+}
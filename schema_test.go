@@ -0,0 +1,50 @@
+package gows
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		data string
+		path string
+	}{
+		{`{"name": "x", "age": 1, "tags": ["a"]}`, ""},
+		{`{"age": 1}`, "$"},
+		{`{"name": ""}`, "$.name"},
+		{`{"name": "x", "age": 1.5}`, "$.age"},
+		{`{"name": "x", "tags": ["c"]}`, "$.tags[0]"},
+		{`{"name": "x", "extra": 1}`, "$"},
+	} {
+		var v interface{}
+		if err = json.Unmarshal([]byte(c.data), &v); err != nil {
+			t.Fatal(err)
+		}
+		err = schema.Validate(v)
+		if c.path == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", c.data, err)
+			}
+			continue
+		}
+		var se *SchemaError
+		if !errors.As(err, &se) || se.Path != c.path || !errors.Is(err, ErrSchemaValidation) {
+			t.Fatalf("%s: got %v, want error at %s", c.data, err, c.path)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gows
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync/atomic"
+)
+
+// signatureSeparator 文本消息中内容与签名的分隔符
+const signatureSeparator = '.'
+
+// Signer 基于 HMAC-SHA256 的消息签名拦截器, 出站消息追加签名, 入站消息校验并去除签名.
+// 二进制消息的签名直接追加在内容之后, 文本消息以 "内容.base64url(签名)" 的形式追加.
+// 开启防重放后, 内容与签名之间还会附带时间戳与随机数, 双方需使用相同的 SignerOptions.
+type Signer struct {
+	// rejected 校验失败的消息数
+	rejected uint64
+	// keys 签名密钥
+	keys KeyProvider
+	// replay 防重放校验, 未开启时为 nil
+	replay *replayGuard
+}
+
+// NewSigner 新建 Signer实例.
+func NewSigner(keys KeyProvider, opts ...*SignerOptions) *Signer {
+	s := &Signer{keys: keys}
+	if len(opts) > 0 && opts[0] != nil && opts[0].ReplayWindow > 0 {
+		s.replay = newReplayGuard(opts[0].ReplayWindow)
+	}
+	return s
+}
+
+// Use 在连接上注册签名与校验拦截器
+func (s *Signer) Use(conn *Connection) {
+	conn.UseInbound(s.Verify)
+	conn.UseOutbound(s.Sign)
+}
+
+// Sign 为消息追加签名, 可作为出站拦截器使用
+func (s *Signer) Sign(conn *Connection, msg *Message) (*Message, error) {
+	content := msg.Data
+	if s.replay != nil {
+		var err error
+		if content, err = s.replay.seal(msg.MessageType, content, conn.clock.Now()); err != nil {
+			return nil, err
+		}
+	}
+	mac, err := s.mac(conn, content)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, len(content)+1+base64.RawURLEncoding.EncodedLen(len(mac)))
+	data = append(data, content...)
+	if msg.MessageType == TextMessage {
+		data = append(data, signatureSeparator)
+		data = append(data, base64.RawURLEncoding.EncodeToString(mac)...)
+	} else {
+		data = append(data, mac...)
+	}
+	return msg.withData(data), nil
+}
+
+// Verify 校验并去除消息签名, 可作为入站拦截器使用. 签名缺失或不匹配时返回 ErrInvalidSignature,
+// 开启防重放时消息超出时间窗口或随机数重复返回 ErrReplayed
+func (s *Signer) Verify(conn *Connection, msg *Message) (*Message, error) {
+	data, sig, ok := s.split(msg)
+	if !ok {
+		atomic.AddUint64(&s.rejected, 1)
+		return nil, ErrInvalidSignature
+	}
+	mac, err := s.mac(conn, data)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, mac) {
+		atomic.AddUint64(&s.rejected, 1)
+		return nil, ErrInvalidSignature
+	}
+	if s.replay != nil {
+		if data, err = s.replay.open(msg.MessageType, data, conn.clock.Now()); err != nil {
+			atomic.AddUint64(&s.rejected, 1)
+			return nil, err
+		}
+	}
+	return msg.withData(data), nil
+}
+
+// Rejected 返回校验失败的消息数
+func (s *Signer) Rejected() uint64 {
+	return atomic.LoadUint64(&s.rejected)
+}
+
+// split 拆分消息内容与签名
+func (s *Signer) split(msg *Message) (data, sig []byte, ok bool) {
+	if msg.MessageType != TextMessage {
+		if len(msg.Data) < sha256.Size {
+			return nil, nil, false
+		}
+		n := len(msg.Data) - sha256.Size
+		return msg.Data[:n], msg.Data[n:], true
+	}
+	i := bytes.LastIndexByte(msg.Data, signatureSeparator)
+	if i < 0 {
+		return nil, nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(string(msg.Data[i+1:]))
+	if err != nil {
+		return nil, nil, false
+	}
+	return msg.Data[:i], sig, true
+}
+
+// mac 计算消息签名
+func (s *Signer) mac(conn *Connection, data []byte) ([]byte, error) {
+	key, err := s.keys.Key(conn)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(data)
+	return h.Sum(nil), nil
+}
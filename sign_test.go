@@ -0,0 +1,80 @@
+package gows
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner(StaticKey([]byte("secret")))
+	conn := NewConnection()
+	for _, msgType := range []int{TextMessage, BinaryMessage} {
+		signed, err := s.Sign(conn, &Message{MessageType: msgType, Data: []byte("a.b"), Deadline: time.Unix(100, 0), OrderKey: "chat-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !signed.Deadline.Equal(time.Unix(100, 0)) || signed.OrderKey != "chat-1" {
+			t.Fatalf("got deadline %v order key %q, want metadata kept", signed.Deadline, signed.OrderKey)
+		}
+		verified, err := s.Verify(conn, signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(verified.Data) != "a.b" {
+			t.Fatalf("got %q, want a.b", verified.Data)
+		}
+		tampered := &Message{MessageType: msgType, Data: bytes.Replace(signed.Data, []byte("a"), []byte("x"), 1)}
+		if _, err = s.Verify(conn, tampered); err != ErrInvalidSignature {
+			t.Fatalf("got %v, want ErrInvalidSignature", err)
+		}
+	}
+	if s.Rejected() != 2 {
+		t.Fatalf("got %d rejected, want 2", s.Rejected())
+	}
+}
+
+// skewClock 在真实时间上叠加固定偏移的时钟
+type skewClock struct {
+	realClock
+	skew time.Duration
+}
+
+func (c skewClock) Now() time.Time {
+	return time.Now().Add(c.skew)
+}
+
+func TestSignerReplay(t *testing.T) {
+	s := NewSigner(StaticKey([]byte("secret")), &SignerOptions{ReplayWindow: time.Minute})
+	conn := NewConnection()
+	for _, msgType := range []int{TextMessage, BinaryMessage} {
+		signed, err := s.Sign(conn, &Message{MessageType: msgType, Data: []byte("a.b")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		verified, err := s.Verify(conn, signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(verified.Data) != "a.b" {
+			t.Fatalf("got %q, want a.b", verified.Data)
+		}
+		if _, err = s.Verify(conn, signed); err != ErrReplayed {
+			t.Fatalf("got %v, want ErrReplayed", err)
+		}
+	}
+	stale := NewConnection(WithClock(skewClock{skew: -2 * time.Minute}))
+	signed, err := s.Sign(stale, &Message{MessageType: TextMessage, Data: []byte("a.b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Verify(conn, signed); err != ErrReplayed {
+		t.Fatalf("got %v, want ErrReplayed for stale timestamp", err)
+	}
+	if _, err = NewSigner(StaticKey([]byte("secret"))).Verify(conn, signed); err != nil {
+		t.Fatalf("got %v, want signature valid without replay protection", err)
+	}
+	if s.Rejected() != 3 {
+		t.Fatalf("got %d rejected, want 3", s.Rejected())
+	}
+}
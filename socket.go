@@ -0,0 +1,42 @@
+package gows
+
+import (
+	"net"
+	"time"
+)
+
+// SocketHook 连接升级后对底层连接进行设置, 如 TCP 参数. 返回 error 时 Open 失败并关闭连接
+type SocketHook func(conn net.Conn) error
+
+// TuneTCP 返回设置 TCP 参数的 SocketHook. noDelay 为 false 时开启 Nagle 算法以减少小包, 为 true 时与 Go 的默认行为一致;
+// keepAlive 大于 0 时以该间隔开启 TCP keepalive, 小于 0 时关闭, 等于 0 时保持默认.
+// 非 TCP 连接(如直接提供 TLS 服务时的 *tls.Conn)不做设置
+func TuneTCP(noDelay bool, keepAlive time.Duration) SocketHook {
+	return func(conn net.Conn) error {
+		tcp, ok := conn.(*net.TCPConn)
+		if !ok {
+			return nil
+		}
+		if err := tcp.SetNoDelay(noDelay); err != nil {
+			return err
+		}
+		switch {
+		case keepAlive > 0:
+			if err := tcp.SetKeepAlive(true); err != nil {
+				return err
+			}
+			return tcp.SetKeepAlivePeriod(keepAlive)
+		case keepAlive < 0:
+			return tcp.SetKeepAlive(false)
+		}
+		return nil
+	}
+}
+
+// netConn 获取底层连接, 缓冲写入模式下返回被包装的连接
+func (c *Connection) netConn() net.Conn {
+	if c.buffered != nil {
+		return c.buffered.Conn
+	}
+	return c.conn.UnderlyingConn()
+}
@@ -0,0 +1,55 @@
+package gows
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketHook(t *testing.T) {
+	errRejected := errors.New("rejected")
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{"tune", []Option{WithSocketHook(TuneTCP(false, 30*time.Second))}, nil},
+		{"buffered", []Option{WithBufferedWrites(0), WithSocketHook(TuneTCP(true, -1))}, nil},
+		{"error", []Option{WithSocketHook(func(conn net.Conn) error { return errRejected })}, errRejected},
+	} {
+		results := make(chan error, 1)
+		types := make(chan string, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var seen string
+			conn := NewConnection(append(c.opts, WithSocketHook(func(nc net.Conn) error {
+				if _, ok := nc.(*net.TCPConn); ok {
+					seen = "tcp"
+				}
+				hook := buildOptions(c.opts).SocketHook
+				return hook(nc)
+			}))...)
+			err := conn.Open(w, r)
+			types <- seen
+			results <- err
+			if err == nil {
+				_ = conn.Close()
+			}
+		}))
+		client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err == nil {
+			_ = client.Close()
+		}
+		if got := <-types; got != "tcp" {
+			t.Fatalf("%s: hook got %q conn, want tcp", c.name, got)
+		}
+		if err = <-results; !errors.Is(err, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.name, err, c.want)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,74 @@
+package gows
+
+import "sync/atomic"
+
+// globalTraffic 所有连接收发的帧数与字节数
+var globalTraffic traffic
+
+// Stats 收发统计
+type Stats struct {
+	// FramesIn 收到的数据帧数
+	FramesIn int64
+	// FramesOut 发出的数据帧数
+	FramesOut int64
+	// BytesIn 收到的字节数, 为入站拦截器处理前的消息长度
+	BytesIn int64
+	// BytesOut 发出的字节数, 为出站拦截器处理后的消息长度
+	BytesOut int64
+}
+
+// traffic 收发计数器, 原子读写, 字段均为 int64 以保证64位对齐
+type traffic struct {
+	// framesIn 收到的数据帧数
+	framesIn int64
+	// framesOut 发出的数据帧数
+	framesOut int64
+	// bytesIn 收到的字节数
+	bytesIn int64
+	// bytesOut 发出的字节数
+	bytesOut int64
+}
+
+// Stats 获取连接的收发统计, 可用于按客户端计量用量. 不包含控制帧与写入失败的消息
+func (c *Connection) Stats() Stats {
+	return c.traffic.load()
+}
+
+// GlobalStats 获取所有连接的收发统计
+func GlobalStats() Stats {
+	return globalTraffic.load()
+}
+
+// countIn 记录收到一帧, 同时累加到全局统计
+func (c *Connection) countIn(n int) {
+	c.traffic.in(n)
+	globalTraffic.in(n)
+}
+
+// countOut 记录发出一帧, 同时累加到全局统计
+func (c *Connection) countOut(n int) {
+	c.traffic.out(n)
+	globalTraffic.out(n)
+}
+
+// in 记录收到一帧
+func (t *traffic) in(n int) {
+	atomic.AddInt64(&t.framesIn, 1)
+	atomic.AddInt64(&t.bytesIn, int64(n))
+}
+
+// out 记录发出一帧
+func (t *traffic) out(n int) {
+	atomic.AddInt64(&t.framesOut, 1)
+	atomic.AddInt64(&t.bytesOut, int64(n))
+}
+
+// load 读取计数
+func (t *traffic) load() Stats {
+	return Stats{
+		FramesIn:  atomic.LoadInt64(&t.framesIn),
+		FramesOut: atomic.LoadInt64(&t.framesOut),
+		BytesIn:   atomic.LoadInt64(&t.bytesIn),
+		BytesOut:  atomic.LoadInt64(&t.bytesOut),
+	}
+}
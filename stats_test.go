@@ -0,0 +1,39 @@
+package gows
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	before := GlobalStats()
+	done := make(chan Stats, 1)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		msg, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		if err = conn.WriteSync(context.Background(), &Message{MessageType: TextMessage, Data: append(msg.Data, msg.Data...)}); err != nil {
+			t.Error(err)
+		}
+		done <- conn.Stats()
+	})
+	defer client.Close()
+	if err := client.WriteMessage(TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	var stats Stats
+	select {
+	case stats = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	want := Stats{FramesIn: 1, FramesOut: 1, BytesIn: 4, BytesOut: 8}
+	if stats != want {
+		t.Fatalf("got %+v, want %+v", stats, want)
+	}
+	if global := GlobalStats(); global.FramesIn-before.FramesIn < 1 || global.BytesOut-before.BytesOut < 8 {
+		t.Fatalf("global stats not updated: before %+v, after %+v", before, global)
+	}
+}
@@ -0,0 +1,27 @@
+package gows
+
+import "context"
+
+// WriteSync 写入数据并等待其被写入底层连接, 返回写入结果. 适合需要比 "已入队" 更强保证的场景.
+// ctx 取消时立即返回 ctx.Err(), 但已入队的消息仍会被发送; 写入失败后即使配置了重试队列也返回该次的错误.
+// 缓冲写入模式下消息写入缓冲区即返回
+func (c *Connection) WriteSync(ctx context.Context, msg *Message) error {
+	done := make(chan error, 1)
+	if err := c.enqueue(c.outChan, pendingMessage{msg: msg, size: len(msg.Data), done: done}); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closeChan:
+		// 连接关闭前消息可能已写入, 写协程退出时会通知仍在队列中的消息
+		select {
+		case err := <-done:
+			return err
+		default:
+			return ErrConnClose
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package gows
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteSync(t *testing.T) {
+	errRejected := errors.New("rejected")
+	results := make(chan error, 2)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		conn.UseOutbound(func(conn *Connection, msg *Message) (*Message, error) {
+			if string(msg.Data) == "bad" {
+				return nil, errRejected
+			}
+			return msg, nil
+		})
+		ctx := context.Background()
+		results <- conn.WriteSync(ctx, &Message{MessageType: TextMessage, Data: []byte("good")})
+		results <- conn.WriteSync(ctx, &Message{MessageType: TextMessage, Data: []byte("bad")})
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, data, err := client.ReadMessage(); err != nil || string(data) != "good" {
+		t.Fatalf("got %q, %v", data, err)
+	}
+	if err := <-results; err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	if err := <-results; !errors.Is(err, errRejected) {
+		t.Fatalf("got %v, want interceptor error", err)
+	}
+
+	conn := NewConnection()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := conn.WriteSync(ctx, &Message{MessageType: TextMessage}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	_ = conn.Close()
+	if err := conn.WriteSync(context.Background(), &Message{MessageType: TextMessage}); !errors.Is(err, ErrConnClose) {
+		t.Fatalf("got %v, want ErrConnClose", err)
+	}
+}
@@ -0,0 +1,30 @@
+package gows
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// ClientCertTLSConfig 返回要求客户端证书的服务端 TLS 配置, clientCAs 为签发客户端证书的 CA.
+// 用于直接以 TLS 提供服务的场景, 如 http.Server.TLSConfig
+func ClientCertTLSConfig(clientCAs *x509.CertPool, certificates ...tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: certificates,
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// PeerCertificate 获取客户端在 TLS 握手中出示并通过校验的证书, 非 TLS 连接或未出示证书时为 nil
+func (c *Connection) PeerCertificate() *x509.Certificate {
+	if c.tlsState == nil || len(c.tlsState.PeerCertificates) == 0 {
+		return nil
+	}
+	return c.tlsState.PeerCertificates[0]
+}
+
+// TLSState 获取 TLS 连接状态, 非 TLS 连接时为 nil
+func (c *Connection) TLSState() *tls.ConnectionState {
+	return c.tlsState
+}
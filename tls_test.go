@@ -0,0 +1,83 @@
+package gows
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/gorilla/websocket"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCert 生成证书, parent 为 nil 时生成自签名的 CA 证书
+func newTestCert(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClientCertUser(t *testing.T) {
+	ca := newTestCert(t, "test-ca", nil)
+	clientCert := newTestCert(t, "alice", &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	users := make(chan string, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithCertUser(func(cert *x509.Certificate) string {
+			return cert.Subject.CommonName
+		}))
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		users <- conn.GetUserID()
+	}))
+	srv.TLS = ClientCertTLSConfig(pool)
+	srv.StartTLS()
+	defer srv.Close()
+	dialer := websocket.Dialer{TLSClientConfig: &tls.Config{
+		RootCAs:      srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+		Certificates: []tls.Certificate{clientCert},
+	}}
+	client, _, err := dialer.Dial("wss"+strings.TrimPrefix(srv.URL, "https"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if got := <-users; got != "alice" {
+		t.Fatalf("got user %q, want alice", got)
+	}
+}
@@ -0,0 +1,110 @@
+package gows
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// topicQueues 按主题划分的写队列, 写协程按主题轮流取出消息, 避免高频主题饿死其他主题
+type topicQueues struct {
+	// count 所有主题待发送的消息数, 原子读写, 放在结构体开头保证64位对齐
+	count int64
+	// mutex 保护以下字段
+	mutex sync.Mutex
+	// size 每个主题队列的容量
+	size int
+	// queues 各主题待发送的消息
+	queues map[string][]pendingMessage
+	// order 有待发送消息的主题, 按轮转顺序排列
+	order []string
+	// notify 有待发送消息的通知
+	notify chan struct{}
+}
+
+// newTopicQueues 新建 topicQueues, size 为每个主题队列的容量
+func newTopicQueues(size int) *topicQueues {
+	return &topicQueues{
+		size:   size,
+		queues: make(map[string][]pendingMessage),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push 追加消息, 主题队列已满时返回 false
+func (q *topicQueues) push(topic string, pending pendingMessage) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	queue, ok := q.queues[topic]
+	if len(queue) >= q.size {
+		return false
+	}
+	if !ok {
+		q.order = append(q.order, topic)
+	}
+	q.queues[topic] = append(queue, pending)
+	atomic.AddInt64(&q.count, 1)
+	q.signal()
+	return true
+}
+
+// pop 按主题轮流取出一条消息
+func (q *topicQueues) pop() (pendingMessage, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.order) == 0 {
+		return pendingMessage{}, false
+	}
+	topic := q.order[0]
+	queue := q.queues[topic]
+	pending := queue[0]
+	queue[0] = pendingMessage{}
+	atomic.AddInt64(&q.count, -1)
+	q.order = q.order[1:]
+	if len(queue) > 1 {
+		q.queues[topic] = queue[1:]
+		q.order = append(q.order, topic)
+	} else {
+		delete(q.queues, topic)
+	}
+	if len(q.order) > 0 {
+		q.signal()
+	}
+	return pending, true
+}
+
+// signal 通知写协程有待发送的消息, 调用方需持有 mutex
+func (q *topicQueues) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// WriteTopic 按主题写入数据. 同一连接订阅了多个主题时, 写协程在各主题之间轮流发送,
+// 某个主题消息过多时不会阻塞其他主题. 每个主题队列的容量与写队列相同, 已满时返回 ErrTopicQueueFull
+func (c *Connection) WriteTopic(topic string, msg *Message) error {
+	pending := pendingMessage{msg: msg, size: len(msg.Data), deadline: msg.deadline(c.clock.Now())}
+	if c.latency != nil {
+		pending.enqueuedAt = time.Now()
+	}
+	select {
+	case <-c.closeChan:
+		c.drop(msg, true, ErrConnClose)
+		return ErrConnClose
+	default:
+	}
+	if err := c.reserveMemory(pending.size); err != nil {
+		c.drop(msg, true, err)
+		return err
+	}
+	if !c.topics.push(topic, pending) {
+		c.releaseMemory(pending.size)
+		err := fmt.Errorf("%w: %s", ErrTopicQueueFull, topic)
+		c.drop(msg, true, err)
+		return err
+	}
+	c.enqueued()
+	return nil
+}
@@ -0,0 +1,45 @@
+package gows
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTopicRoundRobin(t *testing.T) {
+	conn := NewConnection(WithOutChanSize(3))
+	for i := 0; i < 3; i++ {
+		_ = conn.WriteTopic("fire", &Message{MessageType: TextMessage, Data: []byte("fire")})
+	}
+	if err := conn.WriteTopic("fire", &Message{MessageType: TextMessage, Data: []byte("fire")}); !errors.Is(err, ErrTopicQueueFull) {
+		t.Fatalf("got %v, want ErrTopicQueueFull", err)
+	}
+	_ = conn.WriteTopic("slow", &Message{MessageType: TextMessage, Data: []byte("slow")})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"fire", "slow", "fire", "fire"} {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("got %q, want %q", data, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gows
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMessageTTL(t *testing.T) {
+	reasons := make(chan error, 1)
+	client := newTestServer(t, WithDeadLetter(func(conn *Connection, msg *Message, outbound bool, reason error) {
+		reasons <- reason
+	}), func(conn *Connection) {
+		_ = conn.Write((&Message{MessageType: TextMessage, Data: []byte("stale")}).WithTTL(-time.Second))
+		_ = conn.Write((&Message{MessageType: TextMessage, Data: []byte("fresh")}).WithTTL(time.Minute))
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "fresh" {
+		t.Fatalf("got %q, want fresh", data)
+	}
+	if err = <-reasons; !errors.Is(err, ErrMessageExpired) {
+		t.Fatalf("got %v, want ErrMessageExpired", err)
+	}
+}
+
+func TestMessageTTLClock(t *testing.T) {
+	// 有效期按连接的时钟在入队时计算
+	conn := NewConnection(WithClock(skewClock{skew: time.Hour}))
+	if err := conn.Write((&Message{MessageType: TextMessage}).WithTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	pending := <-conn.outChan
+	if want := time.Now().Add(time.Hour + time.Minute); pending.deadline.Before(want.Add(-time.Second)) || pending.deadline.After(want) {
+		t.Fatalf("got deadline %v, want about %v", pending.deadline, want)
+	}
+	later := NewConnection(WithClock(skewClock{skew: 2 * time.Hour}))
+	if err := later.write(pending); !errors.Is(err, ErrMessageExpired) {
+		t.Fatalf("got %v, want ErrMessageExpired", err)
+	}
+}
@@ -0,0 +1,47 @@
+package gows
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// globalSessions 所有已关闭连接的会话时长分布, 桶上界从 1s 开始依次翻倍
+var globalSessions = &Histogram{base: time.Second}
+
+// ConnectedAt 获取协议升级完成的时间, 连接未开启时为零值
+func (c *Connection) ConnectedAt() time.Time {
+	at := atomic.LoadInt64(&c.connectedAt)
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+// Uptime 获取连接的在线时长, 连接关闭后为整个会话的时长, 连接未开启时为 0
+func (c *Connection) Uptime() time.Duration {
+	at := atomic.LoadInt64(&c.connectedAt)
+	if at == 0 {
+		return 0
+	}
+	end := atomic.LoadInt64(&c.closedAt)
+	if end == 0 {
+		end = c.clock.Now().UnixNano()
+	}
+	return time.Duration(end - at)
+}
+
+// SessionDurations 获取所有已关闭连接的会话时长分布, 可通过 Snapshot().Quantile 估算 p50/p99 等分位数
+func SessionDurations() *Histogram {
+	return globalSessions
+}
+
+// recordSession 记录连接关闭时间并计入会话时长分布, 连接未开启时忽略
+func (c *Connection) recordSession() {
+	at := atomic.LoadInt64(&c.connectedAt)
+	if at == 0 {
+		return
+	}
+	end := c.clock.Now().UnixNano()
+	atomic.StoreInt64(&c.closedAt, end)
+	globalSessions.Observe(time.Duration(end - at))
+}
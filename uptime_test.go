@@ -0,0 +1,40 @@
+package gows
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUptime(t *testing.T) {
+	before := SessionDurations().Snapshot().Count
+	done := make(chan *Connection, 1)
+	client := newTestServer(t, nil, func(conn *Connection) {
+		if conn.ConnectedAt().IsZero() {
+			t.Error("got zero ConnectedAt after Open")
+		}
+		time.Sleep(10 * time.Millisecond)
+		_ = conn.Close()
+		done <- conn
+	})
+	defer client.Close()
+	var conn *Connection
+	select {
+	case conn = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	uptime := conn.Uptime()
+	if uptime < 10*time.Millisecond {
+		t.Fatalf("got uptime %v, want at least 10ms", uptime)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if conn.Uptime() != uptime {
+		t.Fatal("uptime changed after close")
+	}
+	if after := SessionDurations().Snapshot().Count; after < before+1 {
+		t.Fatalf("got %d sessions, want at least %d", after, before+1)
+	}
+	if NewConnection().Uptime() != 0 {
+		t.Fatal("got non-zero uptime before Open")
+	}
+}
@@ -0,0 +1,42 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteUrgent(t *testing.T) {
+	conn := NewConnection()
+	for _, data := range []string{"bulk-1", "bulk-2"} {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte(data)})
+	}
+	_ = conn.WriteUrgent(&Message{MessageType: TextMessage, Data: []byte("restarting")})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 开启前已入队的消息由写协程按优先级发送
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"restarting", "bulk-1", "bulk-2"} {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("got %q, want %q", data, want)
+		}
+	}
+}
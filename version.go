@@ -0,0 +1,108 @@
+package gows
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultVersionErrorTimeout 协商失败时发送错误回复的超时时间
+const DefaultVersionErrorTimeout = 5 * time.Second
+
+// VersionHandler 处理已协商出协议版本的连接, 返回后连接将被关闭
+type VersionHandler func(conn *Connection)
+
+// VersionNegotiator 协议版本协商器, 同一个端点可同时支持多个版本的客户端.
+// 优先通过子协议(Sec-WebSocket-Protocol)协商, 客户端未携带子协议时以首帧内容作为版本号.
+type VersionNegotiator struct {
+	// FirstFrameTimeout 等待首帧版本号的超时时间, 为 0 时不等待首帧, 直接使用 Default
+	FirstFrameTimeout time.Duration
+	// Default 客户端未指定版本时使用的版本, 为空时拒绝连接
+	Default string
+	// versions 已注册的版本, 按优先级排列
+	versions []string
+	// handlers 各版本的处理函数
+	handlers map[string]VersionHandler
+}
+
+// NewVersionNegotiator 新建 VersionNegotiator实例.
+func NewVersionNegotiator() *VersionNegotiator {
+	return &VersionNegotiator{handlers: make(map[string]VersionHandler)}
+}
+
+// Handle 注册版本处理函数, 先注册的版本优先级更高
+func (n *VersionNegotiator) Handle(version string, h VersionHandler) {
+	if _, ok := n.handlers[version]; !ok {
+		n.versions = append(n.versions, version)
+	}
+	n.handlers[version] = h
+}
+
+// Serve 升级连接并协商版本, 交由对应版本的处理函数处理, 处理函数返回后关闭连接.
+// opts 未设置子协议时使用已注册的版本作为子协议, 已设置时保留调用方的配置, 此时只在客户端选择的子协议
+// 恰好是已注册的版本时才据此协商. 协商失败时向客户端回复结构化错误, 确认写出后再关闭连接, 并返回 ErrUnsupportedVersion
+func (n *VersionNegotiator) Serve(w http.ResponseWriter, r *http.Request, opts ...Option) error {
+	o := buildOptions(opts)
+	if len(o.Subprotocols) == 0 {
+		o.Subprotocols = append([]string(nil), n.versions...)
+	}
+	conn := NewConnection(o)
+	if err := conn.Open(w, r); err != nil {
+		return err
+	}
+	defer conn.Close()
+	version, err := n.negotiate(conn)
+	if err != nil {
+		n.reject(conn, err)
+		return err
+	}
+	conn.version = version
+	n.handlers[version](conn)
+	return nil
+}
+
+// reject 同步写出协商失败的错误回复, 避免连接关闭时回复仍在写队列中被丢弃
+func (n *VersionNegotiator) reject(conn *Connection, err error) {
+	e, encodeErr := NewEnvelope(ErrorEvent, &ErrorPayload{Code: ErrorCodeUnsupportedVersion, Message: err.Error()})
+	if encodeErr != nil {
+		return
+	}
+	msg, encodeErr := e.Message()
+	if encodeErr != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultVersionErrorTimeout)
+	defer cancel()
+	_ = conn.WriteSync(ctx, msg)
+}
+
+// negotiate 协商协议版本
+func (n *VersionNegotiator) negotiate(conn *Connection) (string, error) {
+	if version := conn.Subprotocol(); version != "" {
+		if _, ok := n.handlers[version]; ok {
+			return version, nil
+		}
+	}
+	version := n.Default
+	if n.FirstFrameTimeout > 0 {
+		timer := conn.clock.NewTimer(n.FirstFrameTimeout)
+		defer timer.Stop()
+		msg, err := conn.receive(timer.C())
+		switch err {
+		case nil:
+			version = string(msg.Data)
+		case ErrReceiveTimeout:
+		default:
+			return "", err
+		}
+	}
+	if _, ok := n.handlers[version]; !ok {
+		return "", ErrUnsupportedVersion
+	}
+	return version, nil
+}
+
+// Version 获取协商出的协议版本, 未经 VersionNegotiator 协商时为空
+func (c *Connection) Version() string {
+	return c.version
+}
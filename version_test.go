@@ -0,0 +1,119 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVersionNegotiator(t *testing.T) {
+	n := NewVersionNegotiator()
+	n.FirstFrameTimeout = time.Second
+	for _, v := range []string{"v2", "v1"} {
+		v := v
+		n.Handle(v, func(conn *Connection) {
+			_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte(v + ":" + conn.Version())})
+			_, _ = conn.Receive()
+		})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = n.Serve(w, r)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, c := range []struct {
+		name       string
+		protocols  []string
+		firstFrame string
+		want       string
+	}{
+		{"subprotocol", []string{"v1"}, "", "v1:v1"},
+		{"first frame", nil, "v2", "v2:v2"},
+	} {
+		dialer := websocket.Dialer{Subprotocols: c.protocols}
+		client, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.firstFrame != "" {
+			_ = client.WriteMessage(websocket.TextMessage, []byte(c.firstFrame))
+		}
+		_ = client.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.want {
+			t.Fatalf("%s: got %q, want %q", c.name, data, c.want)
+		}
+		_ = client.Close()
+	}
+}
+
+func TestVersionNegotiatorReject(t *testing.T) {
+	n := NewVersionNegotiator()
+	n.FirstFrameTimeout = time.Second
+	n.Handle("v1", func(conn *Connection) {})
+	errs := make(chan error, 1)
+	opts := []Option{WithInChanSize(8)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs <- n.Serve(w, r, opts...)
+	}))
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_ = client.WriteMessage(websocket.TextMessage, []byte("v9"))
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("version error not delivered before close: %v", err)
+	}
+	e, err := DecodeEnvelope(data)
+	if err != nil || e.Event != ErrorEvent {
+		t.Fatalf("got %s, want an error envelope", data)
+	}
+	var payload ErrorPayload
+	if err = e.Bind(&payload); err != nil || payload.Code != ErrorCodeUnsupportedVersion {
+		t.Fatalf("got %+v, want code %s", payload, ErrorCodeUnsupportedVersion)
+	}
+	if err = <-errs; err != ErrUnsupportedVersion {
+		t.Fatalf("got %v, want ErrUnsupportedVersion", err)
+	}
+	if len(opts) != 1 || cap(opts) != 1 {
+		t.Fatal("caller options modified")
+	}
+}
+
+func TestVersionNegotiatorKeepsSubprotocols(t *testing.T) {
+	n := NewVersionNegotiator()
+	n.Default = "v1"
+	n.Handle("v1", func(conn *Connection) {
+		_ = conn.Write(&Message{MessageType: TextMessage, Data: []byte(conn.Version())})
+		_, _ = conn.Receive()
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = n.Serve(w, r, WithSubprotocols("chat"))
+	}))
+	defer srv.Close()
+	dialer := websocket.Dialer{Subprotocols: []string{"chat"}}
+	client, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.Subprotocol() != "chat" {
+		t.Fatalf("got subprotocol %q, want caller's chat kept", client.Subprotocol())
+	}
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil || string(data) != "v1" {
+		t.Fatalf("got %q %v, want default version v1", data, err)
+	}
+}
@@ -0,0 +1,245 @@
+package gows
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// WebhookEventConnect 连接建立事件
+	WebhookEventConnect = "connect"
+
+	// WebhookEventDisconnect 连接断开事件
+	WebhookEventDisconnect = "disconnect"
+
+	// WebhookSignatureHeader webhook 签名头, 值为请求体的 HMAC-SHA256 十六进制编码
+	WebhookSignatureHeader = "X-Gows-Signature"
+
+	// DefaultWebhookMaxRetries 默认 webhook 最大重试次数
+	DefaultWebhookMaxRetries = 3
+
+	// DefaultWebhookRetryInterval 默认 webhook 首次重试间隔, 之后每次翻倍
+	DefaultWebhookRetryInterval = time.Second
+
+	// DefaultWebhookTimeout 默认 webhook 请求超时时间
+	DefaultWebhookTimeout = 5 * time.Second
+
+	// DefaultWebhookQueueSize 默认 webhook 待发送队列大小
+	DefaultWebhookQueueSize = 1024
+)
+
+// WebhookEvent 连接生命周期事件
+type WebhookEvent struct {
+	// Type 事件类型
+	Type string `json:"type"`
+	// ConnID 连接ID
+	ConnID string `json:"conn_id"`
+	// RemoteAddr 远程地址
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	// Reason 断开原因
+	Reason string `json:"reason,omitempty"`
+	// Timestamp 事件时间, Unix 毫秒
+	Timestamp int64 `json:"ts"`
+}
+
+// WebhookOptions webhook 可选参数
+type WebhookOptions struct {
+	// URLs 接收 webhook 的地址, 每个事件都会发送到所有地址
+	URLs []string
+	// Secret 签名密钥, 为空时不签名
+	Secret []byte
+	// MaxRetries 最大重试次数, 默认3
+	MaxRetries int
+	// RetryInterval 首次重试间隔, 之后每次翻倍, 默认1s
+	RetryInterval time.Duration
+	// Timeout 请求超时时间, 默认5s
+	Timeout time.Duration
+	// QueueSize 待发送队列大小, 队列满时丢弃事件, 默认1024
+	QueueSize int
+	// Logger 日志, 默认不输出
+	Logger Logger
+}
+
+// WebhookDispatcher 将连接生命周期事件以 JSON POST 到配置的地址, 失败时按指数退避重试.
+// OnOpen 与 OnClose 可直接作为连接的回调使用.
+type WebhookDispatcher struct {
+	// opts 配置
+	opts WebhookOptions
+	// client http 客户端
+	client *http.Client
+	// queue 待发送队列
+	queue chan *WebhookEvent
+	// closeChan 关闭通知
+	closeChan chan struct{}
+	// closeOnce 保护 closeChan 只被关闭一次
+	closeOnce sync.Once
+	// wg 等待发送协程退出
+	wg sync.WaitGroup
+}
+
+// NewWebhookDispatcher 新建 WebhookDispatcher实例并启动发送协程.
+func NewWebhookDispatcher(opts *WebhookOptions) *WebhookDispatcher {
+	o := *opts
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = DefaultWebhookMaxRetries
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultWebhookRetryInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultWebhookTimeout
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultWebhookQueueSize
+	}
+	if o.Logger == nil {
+		o.Logger = nopLogger{}
+	}
+	d := &WebhookDispatcher{
+		opts:      o,
+		client:    &http.Client{Timeout: o.Timeout},
+		queue:     make(chan *WebhookEvent, o.QueueSize),
+		closeChan: make(chan struct{}),
+	}
+	d.wg.Add(1)
+	go d.loop()
+	return d
+}
+
+// OnOpen 连接开启回调, 发送 connect 事件
+func (d *WebhookDispatcher) OnOpen(conn *Connection) {
+	d.Dispatch(&WebhookEvent{
+		Type:       WebhookEventConnect,
+		ConnID:     conn.GetConnID(),
+		RemoteAddr: conn.GetRemoteAddr().String(),
+	})
+}
+
+// OnClose 连接关闭回调, 发送 disconnect 事件
+func (d *WebhookDispatcher) OnClose(conn *Connection, reason error) {
+	event := &WebhookEvent{
+		Type:       WebhookEventDisconnect,
+		ConnID:     conn.GetConnID(),
+		RemoteAddr: conn.GetRemoteAddr().String(),
+	}
+	if reason != nil {
+		event.Reason = reason.Error()
+	}
+	d.Dispatch(event)
+}
+
+// Dispatch 异步发送事件, 队列已满或已关闭时丢弃
+func (d *WebhookDispatcher) Dispatch(event *WebhookEvent) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	select {
+	case <-d.closeChan:
+		return
+	default:
+	}
+	select {
+	case d.queue <- event:
+	default:
+		d.opts.Logger.Printf("gows: webhook queue full, %s event of connection %s dropped", event.Type, event.ConnID)
+	}
+}
+
+// Close 停止接收新事件, 等待队列中的事件发送完毕. 关闭后失败的事件不再重试, 正在退避等待的重试立即放弃
+func (d *WebhookDispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.closeChan)
+	})
+	d.wg.Wait()
+}
+
+// loop 发送协程
+func (d *WebhookDispatcher) loop() {
+	defer d.wg.Done()
+	for {
+		select {
+		case event := <-d.queue:
+			d.send(event)
+		case <-d.closeChan:
+			for {
+				select {
+				case event := <-d.queue:
+					d.send(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 将事件发送到所有地址
+func (d *WebhookDispatcher) send(event *WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.opts.Logger.Printf("gows: webhook marshal error: %v", err)
+		return
+	}
+	for _, url := range d.opts.URLs {
+		if err = d.post(url, body); err != nil {
+			d.opts.Logger.Printf("gows: webhook %s %s event of connection %s failed: %v", url, event.Type, event.ConnID, err)
+		}
+	}
+}
+
+// post 发送请求, 失败时按指数退避重试, 关闭后不再重试
+func (d *WebhookDispatcher) post(url string, body []byte) error {
+	interval := d.opts.RetryInterval
+	var err error
+	for i := 0; i <= d.opts.MaxRetries; i++ {
+		if i > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-d.closeChan:
+				timer.Stop()
+				return err
+			}
+			interval *= 2
+		}
+		if err = d.postOnce(url, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// postOnce 发送一次请求, 非 2xx 响应视为失败
+func (d *WebhookDispatcher) postOnce(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(d.opts.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(d.opts.Secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook 计算 webhook 请求体签名, 接收方可用于校验请求来源
+func SignWebhook(secret, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
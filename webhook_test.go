@@ -0,0 +1,73 @@
+package gows
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	secret := []byte("secret")
+	var attempts int32
+	events := make(chan *WebhookEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(WebhookSignatureHeader) != SignWebhook(secret, body) {
+			t.Error("invalid signature")
+		}
+		event := &WebhookEvent{}
+		_ = json.Unmarshal(body, event)
+		events <- event
+	}))
+	defer srv.Close()
+	d := NewWebhookDispatcher(&WebhookOptions{
+		URLs:          []string{srv.URL},
+		Secret:        secret,
+		RetryInterval: time.Millisecond,
+	})
+	defer d.Close()
+	d.Dispatch(&WebhookEvent{Type: WebhookEventConnect, ConnID: "1"})
+	select {
+	case event := <-events:
+		if event.Type != WebhookEventConnect || event.ConnID != "1" {
+			t.Fatalf("got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook not delivered")
+	}
+}
+
+func TestWebhookCloseAbortsRetries(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	d := NewWebhookDispatcher(&WebhookOptions{URLs: []string{srv.URL}, RetryInterval: time.Hour})
+	d.Dispatch(&WebhookEvent{Type: WebhookEventConnect, ConnID: "1"})
+	for atomic.LoadInt32(&attempts) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		d.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on retry backoff")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatalf("got %d attempts, want 1", n)
+	}
+}
@@ -0,0 +1,158 @@
+package gows
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+const (
+	// BinaryEnvelopeSubprotocol 使用二进制信封的子协议, 协商成功时 WriteEnvelope 以二进制格式发送
+	BinaryEnvelopeSubprotocol = "gows.bin.v1"
+
+	// BinaryEnvelopeVersion 当前二进制信封格式版本
+	BinaryEnvelopeVersion = 1
+
+	// binaryFlagHeaders 二进制信封携带头部的标志位
+	binaryFlagHeaders = 1 << 0
+)
+
+// binaryMagic 二进制信封的魔数, 与 JSON 文本的首字节不会冲突
+var binaryMagic = []byte{'G', 'W'}
+
+// MarshalBinary 将信封编码为紧凑的二进制格式, 适用于跨语言的稳定线上格式.
+// 格式依次为: 魔数 "GW", 版本(1字节), 标志位(1字节), 时间戳(8字节大端), 事件名, 消息ID,
+// 头部(标志位置位时, 数量后接键值对), 消息体(剩余全部字节). 字符串与数量均以 uvarint 长度前缀编码
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	var flags byte
+	if len(e.Headers) > 0 {
+		flags |= binaryFlagHeaders
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 16+len(e.Event)+len(e.ID)+len(e.Payload)))
+	buf.Write(binaryMagic)
+	buf.WriteByte(BinaryEnvelopeVersion)
+	buf.WriteByte(flags)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(e.Timestamp))
+	buf.Write(ts[:])
+	writeBinaryString(buf, e.Event)
+	writeBinaryString(buf, e.ID)
+	if flags&binaryFlagHeaders != 0 {
+		keys := make([]string, 0, len(e.Headers))
+		for k := range e.Headers {
+			keys = append(keys, k)
+		}
+		// 按键排序保证相同的信封编码结果一致
+		sort.Strings(keys)
+		writeBinaryUvarint(buf, uint64(len(keys)))
+		for _, k := range keys {
+			writeBinaryString(buf, k)
+			writeBinaryString(buf, e.Headers[k])
+		}
+	}
+	buf.Write(e.Payload)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 解码二进制信封, 格式错误或版本高于 BinaryEnvelopeVersion 时返回 ErrInvalidEnvelope
+func (e *Envelope) UnmarshalBinary(data []byte) error {
+	if !IsBinaryEnvelope(data) || len(data) < len(binaryMagic)+10 {
+		return fmt.Errorf("%w: bad magic", ErrInvalidEnvelope)
+	}
+	r := bytes.NewReader(data[len(binaryMagic):])
+	version, _ := r.ReadByte()
+	if version == 0 || version > BinaryEnvelopeVersion {
+		return fmt.Errorf("%w: unsupported binary version %d", ErrInvalidEnvelope, version)
+	}
+	flags, _ := r.ReadByte()
+	var ts [8]byte
+	_, _ = r.Read(ts[:])
+	out := Envelope{Timestamp: int64(binary.BigEndian.Uint64(ts[:]))}
+	var err error
+	if out.Event, err = readBinaryString(r); err != nil {
+		return err
+	}
+	if out.ID, err = readBinaryString(r); err != nil {
+		return err
+	}
+	if flags&binaryFlagHeaders != 0 {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return fmt.Errorf("%w: bad header count", ErrInvalidEnvelope)
+		}
+		out.Headers = make(map[string]string, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := readBinaryString(r)
+			if err != nil {
+				return err
+			}
+			if out.Headers[k], err = readBinaryString(r); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Len() > 0 {
+		out.Payload = data[len(data)-r.Len():]
+	}
+	*e = out
+	return nil
+}
+
+// DecodeBinaryEnvelope 解码二进制信封, 缺少事件名时返回 ErrInvalidEnvelope
+func DecodeBinaryEnvelope(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := e.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if e.Event == "" {
+		return nil, fmt.Errorf("%w: missing event", ErrInvalidEnvelope)
+	}
+	return e, nil
+}
+
+// IsBinaryEnvelope 判断数据是否以二进制信封的魔数开头
+func IsBinaryEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, binaryMagic)
+}
+
+// DecodeMessageEnvelope 解码消息中的信封, 根据魔数自动识别二进制与 JSON 格式,
+// 便于同一端点同时兼容两种格式的客户端
+func DecodeMessageEnvelope(msg *Message) (*Envelope, error) {
+	if msg.MessageType == BinaryMessage && IsBinaryEnvelope(msg.Data) {
+		return DecodeBinaryEnvelope(msg.Data)
+	}
+	return DecodeEnvelope(msg.Data)
+}
+
+// BinaryMessage 将信封编码为二进制消息
+func (e *Envelope) BinaryMessage() (*Message, error) {
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &Message{MessageType: BinaryMessage, Data: data}, nil
+}
+
+// writeBinaryUvarint 写入 uvarint
+func writeBinaryUvarint(buf *bytes.Buffer, n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+// writeBinaryString 写入带长度前缀的字符串
+func writeBinaryString(buf *bytes.Buffer, s string) {
+	writeBinaryUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// readBinaryString 读取带长度前缀的字符串
+func readBinaryString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", fmt.Errorf("%w: truncated string", ErrInvalidEnvelope)
+	}
+	s := make([]byte, n)
+	_, _ = r.Read(s)
+	return string(s), nil
+}
@@ -0,0 +1,85 @@
+package gows
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBinaryEnvelope(t *testing.T) {
+	e, err := NewEnvelope("chat", map[string]string{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Headers = map[string]string{"trace": "1", "a": "b"}
+	data, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := e.MarshalBinary()
+	if !bytes.Equal(data, again) {
+		t.Fatal("encoding is not deterministic")
+	}
+	got, err := DecodeMessageEnvelope(&Message{MessageType: BinaryMessage, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != e.ID || got.Event != "chat" || got.Timestamp != e.Timestamp || got.Headers["trace"] != "1" || string(got.Payload) != string(e.Payload) {
+		t.Fatalf("got %+v, want %+v", got, e)
+	}
+	for _, bad := range [][]byte{
+		data[:len(binaryMagic)+5],
+		append([]byte{'G', 'W', BinaryEnvelopeVersion + 1}, data[3:]...),
+		data[:len(binaryMagic)+12],
+	} {
+		if _, err = DecodeBinaryEnvelope(bad); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Fatalf("got %v, want ErrInvalidEnvelope", err)
+		}
+	}
+	text, _ := e.Encode()
+	if got, err = DecodeMessageEnvelope(&Message{MessageType: TextMessage, Data: text}); err != nil || got.ID != e.ID {
+		t.Fatalf("got %+v, %v, want JSON envelope decoded", got, err)
+	}
+}
+
+func TestBinaryEnvelopeNegotiation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := NewConnection(WithSubprotocols(BinaryEnvelopeSubprotocol))
+		if err := conn.Open(w, r); err != nil {
+			return
+		}
+		defer conn.Close()
+		e, _ := NewEnvelope("hello", nil)
+		_ = conn.WriteEnvelope(e)
+		_, _ = conn.Receive()
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	for _, tc := range []struct {
+		protocols []string
+		want      int
+	}{
+		{protocols: []string{BinaryEnvelopeSubprotocol}, want: websocket.BinaryMessage},
+		{protocols: nil, want: websocket.TextMessage},
+	} {
+		dialer := websocket.Dialer{Subprotocols: tc.protocols}
+		client, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = client.SetReadDeadline(time.Now().Add(time.Second))
+		msgType, data, err := client.ReadMessage()
+		_ = client.Close()
+		if err != nil || msgType != tc.want {
+			t.Fatalf("protocols %v: got type %d, %v, want %d", tc.protocols, msgType, err, tc.want)
+		}
+		if e, err := DecodeMessageEnvelope(&Message{MessageType: msgType, Data: data}); err != nil || e.Event != "hello" {
+			t.Fatalf("protocols %v: got %+v, %v", tc.protocols, e, err)
+		}
+	}
+}
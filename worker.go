@@ -0,0 +1,149 @@
+package gows
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
+
+// DefaultWorkerQueueSize 默认工作池任务队列大小
+const DefaultWorkerQueueSize = 1024
+
+// Handler 消息处理函数
+type Handler func(conn *Connection, msg *Message)
+
+// WorkerPoolOptions 工作池可选参数
+type WorkerPoolOptions struct {
+	// Workers 工作协程数, 默认 runtime.NumCPU()
+	Workers int
+	// QueueSize 任务队列大小, 队列满时提交将阻塞, 默认1024
+	QueueSize int
+	// Ordered 为 true 时同一连接的消息按接收顺序依次处理
+	Ordered bool
+	// OrderKey 返回消息的顺序键, 如会话ID. 非 nil 时顺序键相同的消息按提交顺序依次处理, 不论来自哪个连接,
+	// 不同顺序键的消息并行处理. 处理函数按顺序写入的消息再配合 Message.OrderKey 即可保证端到端有序
+	OrderKey func(conn *Connection, msg *Message) string
+}
+
+// task 待处理的消息
+type task struct {
+	conn *Connection
+	msg  *Message
+}
+
+// WorkerPool 有界工作池, 入站消息交由固定数量的协程处理, 慢处理函数不会阻塞连接的读取.
+type WorkerPool struct {
+	// handler 消息处理函数
+	handler Handler
+	// ordered 是否保证同一连接的消息顺序
+	ordered bool
+	// orderKey 返回消息的顺序键
+	orderKey func(conn *Connection, msg *Message) string
+	// queues 任务队列, 有序模式下每个工作协程一个队列, 否则共用一个队列
+	queues []chan task
+	// mutex 保护 closed
+	mutex sync.RWMutex
+	// closed 是否已关闭
+	closed bool
+	// wg 等待工作协程退出
+	wg sync.WaitGroup
+}
+
+// NewWorkerPool 新建 WorkerPool实例并启动工作协程.
+func NewWorkerPool(handler Handler, opts ...*WorkerPoolOptions) *WorkerPool {
+	workers, queueSize, ordered := runtime.NumCPU(), DefaultWorkerQueueSize, false
+	var orderKey func(conn *Connection, msg *Message) string
+	if len(opts) > 0 && opts[0] != nil {
+		opt := opts[0]
+		if opt.Workers > 0 {
+			workers = opt.Workers
+		}
+		if opt.QueueSize > 0 {
+			queueSize = opt.QueueSize
+		}
+		ordered, orderKey = opt.Ordered || opt.OrderKey != nil, opt.OrderKey
+	}
+	p := &WorkerPool{handler: handler, ordered: ordered, orderKey: orderKey}
+	if ordered {
+		p.queues = make([]chan task, workers)
+		for i := range p.queues {
+			p.queues[i] = make(chan task, queueSize)
+		}
+	} else {
+		p.queues = []chan task{make(chan task, queueSize)}
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work(p.queues[i%len(p.queues)])
+	}
+	return p
+}
+
+// Serve 持续读取连接的消息并提交到工作池, 连接关闭时返回 ErrConnClose
+func (p *WorkerPool) Serve(conn *Connection) error {
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+		// 零拷贝接收模式下消息在下一次 Receive 后失效, 异步处理前需要拷贝
+		if conn.zeroCopy {
+			msg = msg.Clone()
+		}
+		if err = p.Submit(conn, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// Submit 提交消息, 队列满时阻塞, 工作池已关闭时返回 ErrWorkerPoolClosed
+func (p *WorkerPool) Submit(conn *Connection, msg *Message) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		return ErrWorkerPoolClosed
+	}
+	select {
+	case p.queue(conn, msg) <- task{conn: conn, msg: msg}:
+		return nil
+	case <-conn.closeChan:
+		return ErrConnClose
+	}
+}
+
+// Close 关闭工作池, 等待已提交的消息处理完毕
+func (p *WorkerPool) Close() {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.mutex.Unlock()
+	p.wg.Wait()
+}
+
+// queue 选择消息对应的任务队列, 默认按连接划分, 设置了 OrderKey 时按顺序键划分
+func (p *WorkerPool) queue(conn *Connection, msg *Message) chan task {
+	if len(p.queues) == 1 {
+		return p.queues[0]
+	}
+	key := conn.id
+	if p.orderKey != nil {
+		key = p.orderKey(conn, msg)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return p.queues[h.Sum32()%uint32(len(p.queues))]
+}
+
+// work 工作协程
+func (p *WorkerPool) work(queue chan task) {
+	defer p.wg.Done()
+	for t := range queue {
+		t.conn.protect(func() {
+			p.handler(t.conn, t.msg)
+		})
+	}
+}
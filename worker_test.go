@@ -0,0 +1,66 @@
+package gows
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWorkerPoolOrdered(t *testing.T) {
+	var mutex sync.Mutex
+	got := make(map[*Connection][]int)
+	p := NewWorkerPool(func(conn *Connection, msg *Message) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		got[conn] = append(got[conn], int(msg.Data[0]))
+	}, &WorkerPoolOptions{Workers: 4, Ordered: true})
+	conns := []*Connection{NewConnection(), NewConnection(), NewConnection()}
+	for i := 0; i < 100; i++ {
+		for _, conn := range conns {
+			if err := p.Submit(conn, &Message{Data: []byte{byte(i)}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	p.Close()
+	for _, conn := range conns {
+		if len(got[conn]) != 100 {
+			t.Fatalf("got %d messages, want 100", len(got[conn]))
+		}
+		for i, v := range got[conn] {
+			if v != i {
+				t.Fatalf("message %d out of order: %v", i, got[conn])
+			}
+		}
+	}
+	if err := p.Submit(conns[0], &Message{}); err != ErrWorkerPoolClosed {
+		t.Fatalf("got %v, want ErrWorkerPoolClosed", err)
+	}
+}
+
+func TestWorkerPoolOrderKey(t *testing.T) {
+	var mutex sync.Mutex
+	var got []int
+	p := NewWorkerPool(func(conn *Connection, msg *Message) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		got = append(got, int(msg.Data[0]))
+	}, &WorkerPoolOptions{Workers: 4, OrderKey: func(conn *Connection, msg *Message) string {
+		return msg.OrderKey
+	}})
+	// 不同连接上顺序键相同的消息按提交顺序处理
+	conns := []*Connection{NewConnection(), NewConnection()}
+	for i := 0; i < 100; i++ {
+		if err := p.Submit(conns[i%2], &Message{Data: []byte{byte(i)}, OrderKey: "chat-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+	if len(got) != 100 {
+		t.Fatalf("got %d messages, want 100", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("message %d out of order: %v", i, got)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package gows
+
+import "encoding/json"
+
+// WriteText 写入文本消息, 经写队列发送, 可并发调用
+func (c *Connection) WriteText(text string) error {
+	return c.Write(&Message{MessageType: TextMessage, Data: []byte(text)})
+}
+
+// WriteBinary 写入二进制消息, 经写队列发送, 可并发调用. 消息发送前 data 不可修改
+func (c *Connection) WriteBinary(data []byte) error {
+	return c.Write(&Message{MessageType: BinaryMessage, Data: data})
+}
+
+// WriteJSON 将 v 编码为 JSON 后以文本消息写入, 经写队列发送, 可并发调用
+func (c *Connection) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Write(&Message{MessageType: TextMessage, Data: data})
+}
@@ -0,0 +1,38 @@
+package gows
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteHelpers(t *testing.T) {
+	client := newTestServer(t, nil, func(conn *Connection) {
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() { defer wg.Done(); _ = conn.WriteText("text") }()
+		go func() { defer wg.Done(); _ = conn.WriteBinary([]byte("binary")) }()
+		go func() { defer wg.Done(); _ = conn.WriteJSON(map[string]int{"n": 1}) }()
+		wg.Wait()
+		if err := conn.WriteJSON(func() {}); err == nil {
+			t.Error("got nil, want JSON encoding error")
+		}
+		_, _ = conn.Receive()
+	})
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	got := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		msgType, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[string(data)] = msgType
+	}
+	want := map[string]int{"text": TextMessage, "binary": BinaryMessage, `{"n":1}`: TextMessage}
+	for data, msgType := range want {
+		if got[data] != msgType {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gows
+
+import "sync/atomic"
+
+// readMessage 读取一条消息, 零拷贝接收模式下复用读缓冲区
+func (c *Connection) readMessage() (int, []byte, error) {
+	if !c.zeroCopy {
+		return c.conn.ReadMessage()
+	}
+	msgType, r, err := c.conn.NextReader()
+	if err != nil {
+		return msgType, nil, err
+	}
+	c.readBuf.Reset()
+	if _, err = c.readBuf.ReadFrom(r); err != nil {
+		return msgType, nil, err
+	}
+	return msgType, c.readBuf.Bytes(), nil
+}
+
+// waitRelease 零拷贝接收模式下等待已交付的消息被释放后才能复用读缓冲区, 连接关闭时返回 false
+func (c *Connection) waitRelease() bool {
+	select {
+	case <-c.releaseChan:
+		return true
+	case <-c.closeChan:
+		return false
+	}
+}
+
+// markReceived 记录已交付一条消息
+func (c *Connection) markReceived() {
+	if c.zeroCopy {
+		atomic.StoreInt32(&c.received, 1)
+	}
+}
+
+// releaseReceived 释放上一次交付的消息, 允许读协程复用读缓冲区
+func (c *Connection) releaseReceived() {
+	if c.zeroCopy && atomic.CompareAndSwapInt32(&c.received, 1, 0) {
+		c.releaseChan <- struct{}{}
+	}
+}
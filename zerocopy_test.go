@@ -0,0 +1,44 @@
+package gows
+
+import (
+	"github.com/gorilla/websocket"
+	"testing"
+	"time"
+)
+
+func TestZeroCopyReceive(t *testing.T) {
+	received := make(chan []string, 1)
+	client := newTestServer(t, WithZeroCopy(), func(conn *Connection) {
+		var kept []*Message
+		var got []string
+		for i := 0; i < 3; i++ {
+			msg, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			got = append(got, string(msg.Data))
+			kept = append(kept, msg.Clone())
+		}
+		for _, msg := range kept {
+			got = append(got, string(msg.Data))
+		}
+		received <- got
+	})
+	defer client.Close()
+	for _, data := range []string{"aaa", "bb", "c"} {
+		if err := client.WriteMessage(websocket.BinaryMessage, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case got := <-received:
+		want := []string{"aaa", "bb", "c", "aaa", "bb", "c"}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}